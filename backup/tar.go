@@ -0,0 +1,98 @@
+/*
+Package backup writes incremental tar archives of walked trees, of the files changed since a snapshot.
+
+It is a package of its own, so that programs not archiving trees do not link package archive/tar, which uses package os/user and so makes binaries dynamically linked.
+*/
+package backup
+
+import (
+	"archive/tar"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/moledoc/walks"
+)
+
+// DeletedListName is the name of the entry listing deleted paths, that Incremental adds to archives with deletions.
+const DeletedListName = ".walks-deleted"
+
+// Incremental writes to out a tar archive of the files under root added or changed since snapshot prev, the core of simple incremental backups.
+// Paths in the archive are relative to root. Paths deleted since prev are returned, and also listed in archive entry DeletedListName (each path terminated by NUL, so that any name is kept intact),
+// so that restoring archives in order reproduces the tree. New directories are archived too, to keep their permissions.
+// With prev nil, all files are archived. The snapshot taken for the archive is returned, to be passed as prev next time.
+// Files are not read, unless Walker w grants CanRead (see walks' Require); w may be nil.
+// Directories and files can be ignored by setting package walks' Ignore value with SetIgnore function or manually before Incremental call.
+func Incremental(w *walks.Walker, out io.Writer, root string, prev *walks.Snapshot) (*walks.Snapshot, []string, error) {
+	if err := w.Require(walks.CanRead, "tar", root); err != nil {
+		return nil, nil, err
+	}
+	cur, err := walks.TakeSnapshot(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	if prev == nil {
+		prev = &walks.Snapshot{Root: root}
+	}
+	tw := tar.NewWriter(out)
+	var deleted []string
+	for _, ev := range prev.Diff(cur) {
+		rel, err := filepath.Rel(root, ev.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if rel == "." {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		if ev.Op == walks.OpDelete {
+			deleted = append(deleted, rel)
+			continue
+		}
+		if err := tarEntry(tw, ev.Path, rel, cur.Entries[ev.Path]); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(deleted) > 0 {
+		list := strings.Join(deleted, "\x00") + "\x00"
+		hdr := &tar.Header{Name: DeletedListName, Mode: 0644, Size: int64(len(list)), ModTime: cur.Taken, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, nil, err
+		}
+		if _, err := io.WriteString(tw, list); err != nil {
+			return nil, nil, err
+		}
+	}
+	return cur, deleted, tw.Close()
+}
+
+// tarEntry writes file or directory path, recorded in a snapshot as entry, to tw under name.
+// Entries that are neither regular files nor directories are skipped.
+func tarEntry(tw *tar.Writer, path string, name string, entry walks.SnapshotEntry) error {
+	if !entry.Mode.IsRegular() && !entry.IsDir() {
+		return nil
+	}
+	info, err := walks.DefaultFS.Lstat(path)
+	if err != nil {
+		return err
+	}
+	hdr, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	hdr.Name = name
+	if info.IsDir() {
+		hdr.Name += "/"
+		return tw.WriteHeader(hdr)
+	}
+	f, err := walks.DefaultFS.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err = io.CopyN(tw, f, hdr.Size)
+	return err
+}
@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// names returns the entry names of tar archive b, sorted.
+func names(t *testing.T, b *bytes.Buffer) []string {
+	t.Helper()
+	var got []string
+	tr := tar.NewReader(b)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, hdr.Name)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestIncremental(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a", "d/b"} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(root, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var full bytes.Buffer
+	snap, _, err := Incremental(nil, &full, root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := names(t, &full), []string{"a", "d/", "d/b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got full archive %v, want %v", got, want)
+	}
+	if err := os.Remove(filepath.Join(root, "a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "c"), []byte("c"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var incr bytes.Buffer
+	_, deleted, err := Incremental(nil, &incr, root, snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a"}; !reflect.DeepEqual(deleted, want) {
+		t.Errorf("got deleted %v, want %v", deleted, want)
+	}
+	if got, want := names(t, &incr), []string{DeletedListName, "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got incremental archive %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+package walks
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// benchTree creates a tree of 20 directories with 100 files each for the benchmarks, returning its root.
+func benchTree(b *testing.B) string {
+	b.Helper()
+	root := b.TempDir()
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("d%02d", i))
+		if err := os.Mkdir(dir, 0755); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < 100; j++ {
+			if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%03d", j)), []byte("data"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+// BenchmarkReadDirStat walks the tree statting every entry, as walks did before reading the entries' types from the listings.
+func BenchmarkReadDirStat(b *testing.B) {
+	root := benchTree(b)
+	var walk func(dir string) int64
+	walk = func(dir string) int64 {
+		infos, err := readDir(OSFS{}, dir)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var size int64
+		for _, info := range infos {
+			if info.IsDir() {
+				size += walk(filepath.Join(dir, info.Name()))
+			} else {
+				size += info.Size()
+			}
+		}
+		return size
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		walk(root)
+	}
+}
+
+// benchWalk walks the tree with w, reading sizes of the files if sizes.
+func benchWalk(b *testing.B, w *Walker, sizes bool) {
+	root := benchTree(b)
+	fileAction := func(e Entry) error {
+		if sizes {
+			if _, err := e.Info(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	dirAction := func(Entry) error { return nil }
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := w.WalkEntries(context.Background(), root, fileAction, dirAction); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkWalkReadDir walks the tree with entry types from the listings, statting directories only.
+func BenchmarkWalkReadDir(b *testing.B) {
+	benchWalk(b, New(), false)
+}
+
+// BenchmarkWalkReadDirStat walks the tree with entry types from the listings, statting each file for its size.
+func BenchmarkWalkReadDirStat(b *testing.B) {
+	benchWalk(b, New(WithCountBytes(true)), true)
+}
+
+// BenchmarkWalkFastStat walks the tree reading sizes with statx(2) (on Linux; elsewhere it is BenchmarkWalkReadDirStat).
+func BenchmarkWalkFastStat(b *testing.B) {
+	benchWalk(b, New(WithCountBytes(true), WithFastStat(StatSize)), true)
+}
@@ -0,0 +1,153 @@
+package walks
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// WriteBodyFile walks recursively given directory structure and writes every entry (including root) to w
+// in Sleuth Kit's body file format (version 3), from which mactime builds forensic timelines, eg of mounted images:
+//
+//	MD5|name|inode|mode_as_string|UID|GID|size|atime|mtime|ctime|crtime
+//
+// With hash set, MD5 of regular files is computed, otherwise it is 0. Modes are written like fls writes them (eg r/rrw-r--r--).
+// Times are in seconds since Unix epoch; values not available on the platform are 0, and so is creation time except on Linux,
+// where it is read with statx (if the filesystem records it). Pipes in names are escaped as \|, like fls does,
+// and line breaks as \n and \r, so that each entry is a single line.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before WriteBodyFile call.
+func WriteBodyFile(w io.Writer, root string, hash bool) error {
+	bw := bufio.NewWriter(w)
+	info, err := DefaultFS.Lstat(root)
+	if err != nil {
+		return err
+	}
+	if err := writeBodyLine(bw, root, info, hash); err != nil {
+		return err
+	}
+	it := NewIterator(root)
+	for e := it.Next(); e != nil; e = it.Next() {
+		info, err := e.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := writeBodyLine(bw, e.Path, info, hash); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// WriteBodyFile writes the bodyfile of root like package's WriteBodyFile does, if w grants CanRead, when hash is set (see Require).
+func (w *Walker) WriteBodyFile(out io.Writer, root string, hash bool) error {
+	if hash {
+		if err := w.Require(CanRead, "hash", root); err != nil {
+			return err
+		}
+	}
+	return WriteBodyFile(out, root, hash)
+}
+
+// writeBodyLine writes the body file line of entry path described by info.
+func writeBodyLine(w io.Writer, path string, info os.FileInfo, hash bool) error {
+	sum := "0"
+	if hash && info.Mode().IsRegular() {
+		h, err := md5File(path)
+		if err != nil {
+			return err
+		}
+		sum = h
+	}
+	name := bodyEscaper.Replace(path)
+	if info.Mode()&os.ModeSymlink != 0 {
+		if target, err := readLink(DefaultFS, path); err == nil {
+			name += " -> " + bodyEscaper.Replace(target)
+		}
+	}
+	var atime, ctime, crtime int64
+	if a, c, ok := fileTimes(info); ok {
+		atime, ctime = a.Unix(), c.Unix()
+	}
+	if b, ok := birthTime(path, info); ok {
+		crtime = b.Unix()
+	}
+	_, ino, _ := fileID(info)
+	uid, gid, _ := fileOwner(info)
+	_, err := fmt.Fprintf(w, "%v|%v|%v|%v|%v|%v|%v|%v|%v|%v|%v\n",
+		sum, name, ino, flsMode(info.Mode()), uid, gid, info.Size(), atime, info.ModTime().Unix(), ctime, crtime)
+	return err
+}
+
+// bodyEscaper escapes names in body file lines.
+var bodyEscaper = strings.NewReplacer("|", `\|`, "\n", `\n`, "\r", `\r`)
+
+// flsMode returns mode in the form fls writes it to body files: the type of the name, a slash and the type and permissions of the inode,
+// eg r/rrw-r--r-- for regular files, d/drwxr-xr-x for directories and l/lrwxrwxrwx for symbolic links.
+func flsMode(mode os.FileMode) string {
+	t := byte('r')
+	switch {
+	case mode&os.ModeDir != 0:
+		t = 'd'
+	case mode&os.ModeSymlink != 0:
+		t = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		t = 'p'
+	case mode&os.ModeSocket != 0:
+		t = 's'
+	case mode&os.ModeCharDevice != 0:
+		t = 'c'
+	case mode&os.ModeDevice != 0:
+		t = 'b'
+	case !mode.IsRegular():
+		t = '-'
+	}
+	b := []byte{t, '/', t}
+	const rwx = "rwxrwxrwx"
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) != 0 {
+			b = append(b, rwx[i])
+		} else {
+			b = append(b, '-')
+		}
+	}
+	// special bits replace the execute permissions, like ls shows them
+	special := []struct {
+		bit  os.FileMode
+		i    int
+		x, n byte
+	}{{os.ModeSetuid, 5, 's', 'S'}, {os.ModeSetgid, 8, 's', 'S'}, {os.ModeSticky, 11, 't', 'T'}}
+	for _, s := range special {
+		if mode&s.bit == 0 {
+			continue
+		}
+		if b[s.i] == '-' {
+			b[s.i] = s.n
+		} else {
+			b[s.i] = s.x
+		}
+	}
+	return string(b)
+}
+
+// md5File returns the hex encoded MD5 sum of the file at path.
+func md5File(path string) (string, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,55 @@
+package walks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlsMode(t *testing.T) {
+	tests := []struct {
+		mode os.FileMode
+		want string
+	}{
+		{0644, "r/rrw-r--r--"},
+		{os.ModeDir | 0755, "d/drwxr-xr-x"},
+		{os.ModeSymlink | 0777, "l/lrwxrwxrwx"},
+		{os.ModeNamedPipe | 0600, "p/prw-------"},
+		{os.ModeDevice | os.ModeCharDevice | 0666, "c/crw-rw-rw-"},
+		{os.ModeSetuid | 0755, "r/rrwsr-xr-x"},
+		{os.ModeDir | os.ModeSticky | 0777, "d/drwxrwxrwt"},
+		{os.ModeSetgid | 0640, "r/rrw-r-S---"},
+	}
+	for _, tt := range tests {
+		if got := flsMode(tt.mode); got != tt.want {
+			t.Errorf("flsMode(%v) = %v, want %v", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestWriteBodyFile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a|b\nc"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := WriteBodyFile(&b, root, true); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %v lines, want 2: %q", len(lines), b.String())
+	}
+	fields := strings.Split(strings.Replace(lines[1], `\|`, "", -1), "|")
+	if len(fields) != 11 {
+		t.Fatalf("got %v fields, want 11: %q", len(fields), lines[1])
+	}
+	if want := filepath.Join(root, `ab\nc`); fields[1] != want {
+		t.Errorf("got name %v, want %v", fields[1], want)
+	}
+	if fields[0] != "8d777f385d3dfec8815d20f7496026dc" || fields[3] != "r/rrw-r--r--" || fields[6] != "4" {
+		t.Errorf("got sum %v, mode %v and size %v, want MD5 of data, r/rrw-r--r-- and 4", fields[0], fields[3], fields[6])
+	}
+}
@@ -0,0 +1,97 @@
+package walks
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// BoundaryKind is the kind of a filesystem boundary crossed by a walk, see Boundary.
+type BoundaryKind int
+
+const (
+	// BoundaryMount is a mount point of another filesystem.
+	BoundaryMount BoundaryKind = iota
+	// BoundarySubvolume is the root of a Btrfs subvolume (including snapshots).
+	BoundarySubvolume
+	// BoundaryDataset is the root of a ZFS dataset.
+	BoundaryDataset
+)
+
+// String returns the name of the kind.
+func (k BoundaryKind) String() string {
+	switch k {
+	case BoundaryMount:
+		return "mount"
+	case BoundarySubvolume:
+		return "subvolume"
+	case BoundaryDataset:
+		return "dataset"
+	}
+	return "unknown"
+}
+
+// Boundary is a directory, where a walk enters another filesystem, Btrfs subvolume or ZFS dataset,
+// so that storage tooling can aggregate results per dataset rather than per directory.
+type Boundary struct {
+	Path string
+	Kind BoundaryKind
+	// FSType is the type of the entered filesystem (eg "btrfs", "zfs", "nfs"), if known (on Linux).
+	FSType string
+	// Device is the device number of the entered filesystem, as reported by stat.
+	Device uint64
+}
+
+// btrfsSubvolumeIno is the inode number of every Btrfs subvolume root.
+const btrfsSubvolumeIno = 256
+
+// boundary reports to Walker's Boundary, if directory child is on another device than its parent or is a Btrfs subvolume root.
+func (w *Walker) boundary(parent *dirNode, child *dirNode) {
+	if w.Boundary == nil || !child.hasID || !parent.hasID {
+		return
+	}
+	if child.dev == parent.dev && child.ino != btrfsSubvolumeIno {
+		return
+	}
+	b := Boundary{Path: child.stats.Path, Kind: BoundaryMount, FSType: fsType(child.stats.Path), Device: child.dev}
+	switch {
+	case b.FSType == "btrfs" && child.ino == btrfsSubvolumeIno:
+		b.Kind = BoundarySubvolume
+	case child.dev == parent.dev:
+		// not a boundary, but a directory with the subvolume root's inode number on another filesystem
+		return
+	case b.FSType == "zfs":
+		b.Kind = BoundaryDataset
+	}
+	w.bus.publish(WalkEvent{Kind: EventBoundary, Path: b.Path, Boundary: &b})
+	w.Boundary(b)
+}
+
+// Boundaries walks recursively given directory structure and returns the boundaries (see Boundary) within it, sorted by path;
+// the first one is root itself, describing the filesystem the walk starts on.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Boundaries call.
+func Boundaries(root string) ([]Boundary, error) {
+	info, err := DefaultFS.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	dev, _, _ := fileID(info)
+	kind := BoundaryMount
+	switch fsType(root) {
+	case "btrfs":
+		kind = BoundarySubvolume
+	case "zfs":
+		kind = BoundaryDataset
+	}
+	var mu sync.Mutex
+	var found []Boundary
+	w := New()
+	w.Boundary = func(b Boundary) {
+		mu.Lock()
+		found = append(found, b)
+		mu.Unlock()
+	}
+	err = w.WalkContext(context.Background(), root, func(string) {}, func(string) {})
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return append([]Boundary{{Path: root, Kind: kind, FSType: fsType(root), Device: dev}}, found...), err
+}
@@ -0,0 +1,107 @@
+package walks
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// BuildRule declares how files matching Pattern are turned into derived outputs, eg "for every *.md produce *.html via fn".
+type BuildRule struct {
+	// Pattern is matched against the base name of walked files with filepath.Match.
+	Pattern string
+	// Output returns the path of the output built from src.
+	// When Output is nil, output is placed next to the source with extension Ext.
+	Output func(src string) string
+	// Ext is the extension of the output, used when Output is nil.
+	Ext string
+	// Build writes the output built from src to w.
+	Build func(src string, w io.Writer) error
+	// Freshness decides whether an existing output is up to date with its source.
+	Freshness Freshness
+}
+
+// BuildResult reports what Build did with each matched source file.
+type BuildResult struct {
+	Built  []string         // sources, whose outputs were (re)built
+	Fresh  []string         // sources, whose outputs were already up to date
+	Failed map[string]error // sources, whose build failed
+}
+
+// buildTarget is one source file and the rule it matched.
+type buildTarget struct {
+	src  string
+	rule *BuildRule
+}
+
+// Build walks root and rebuilds outputs of files matching rules, whose outputs are missing or out of date.
+// For each file only the first matching rule is used.
+// Up to workers builds are run in parallel; if workers is not positive, builds are run one at a time.
+// Every matched file is built even if some builds fail; if any did, returned error summarizes the failures.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Build call.
+func Build(root string, rules []BuildRule, workers int) (BuildResult, error) {
+	var targets []buildTarget
+	fileAction := func(path string) {
+		name := filepath.Base(path)
+		for i := range rules {
+			if ok, _ := filepath.Match(rules[i].Pattern, name); ok {
+				targets = append(targets, buildTarget{src: path, rule: &rules[i]})
+				return
+			}
+		}
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); err != nil {
+		return BuildResult{}, err
+	}
+
+	if workers <= 0 {
+		workers = 1
+	}
+	result := BuildResult{Failed: make(map[string]error)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan buildTarget)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range queue {
+				out := t.rule.output(t.src)
+				built, err := WriteDerived(t.src, out, t.rule.Freshness, t.rule.Build)
+				mu.Lock()
+				switch {
+				case err != nil:
+					result.Failed[t.src] = err
+				case built:
+					result.Built = append(result.Built, t.src)
+				default:
+					result.Fresh = append(result.Fresh, t.src)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, t := range targets {
+		queue <- t
+	}
+	close(queue)
+	wg.Wait()
+
+	sort.Strings(result.Built)
+	sort.Strings(result.Fresh)
+	if len(result.Failed) > 0 {
+		return result, fmt.Errorf("walks: %v of %v builds failed", len(result.Failed), len(targets))
+	}
+	return result, nil
+}
+
+// output returns the path of the output built from src according to rule.
+func (rule *BuildRule) output(src string) string {
+	if rule.Output != nil {
+		return rule.Output(src)
+	}
+	out, _ := DerivedPath(src, "", "", rule.Ext)
+	return out
+}
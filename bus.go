@@ -0,0 +1,94 @@
+package walks
+
+import "sync"
+
+// EventKind is a kind of WalkEvent. Kinds are bit flags, so that subscribers can select several of them with |.
+type EventKind uint
+
+const (
+	// EventFile is published for each walked file, before fileAction is called.
+	EventFile EventKind = 1 << iota
+	// EventDir is published for each walked directory, before dirAction is called.
+	EventDir
+	// EventDirDone is published when a directory and all of its walked subdirectories are processed, with Stats set.
+	EventDirDone
+	// EventVanished is published for each entry removed during the walk (see Walker's Vanished).
+	EventVanished
+	// EventGuard is published for each directory not descended into because of a guardrail, with Err set (see Walker's Guard).
+	EventGuard
+	// EventError is published for the error stopping the walk, with Err set.
+	EventError
+	// EventBoundary is published for each filesystem boundary entered, with Boundary set (see Walker's Boundary).
+	EventBoundary
+
+	// EventAll selects all kinds of events.
+	EventAll EventKind = 1<<iota - 1
+)
+
+// WalkEvent is a notification about the progress of a walk, as delivered to subscribers (see Walker's Subscribe).
+type WalkEvent struct {
+	Kind EventKind
+	Path string
+	// Stats holds the summary of the directory for EventDirDone.
+	Stats *DirStats
+	// Err holds the reason for EventGuard and EventError.
+	Err error
+	// Boundary holds the boundary for EventBoundary.
+	Boundary *Boundary
+}
+
+// bus delivers walk events to subscribers.
+// Subscribers are called synchronously and possibly concurrently, from the goroutines of the walk.
+type bus struct {
+	mu   sync.RWMutex
+	subs map[int]subscriber
+	next int
+}
+
+// subscriber is a function subscribed to events of given kinds.
+type subscriber struct {
+	kinds EventKind
+	fn    func(WalkEvent)
+}
+
+// Subscribe attaches fn to walks done with w, calling it with every event of given kinds; kinds can be combined with |, or be EventAll.
+// This lets several independent consumers (progress, statistics, audit logs, metrics) observe one walk without dedicated callbacks.
+// fn is called synchronously from the walk and may be called concurrently. The returned function unsubscribes fn.
+func (w *Walker) Subscribe(kinds EventKind, fn func(WalkEvent)) (unsubscribe func()) {
+	w.bus.mu.Lock()
+	defer w.bus.mu.Unlock()
+	if w.bus.subs == nil {
+		w.bus.subs = make(map[int]subscriber)
+	}
+	id := w.bus.next
+	w.bus.next++
+	w.bus.subs[id] = subscriber{kinds: kinds, fn: fn}
+	return func() {
+		w.bus.mu.Lock()
+		defer w.bus.mu.Unlock()
+		delete(w.bus.subs, id)
+	}
+}
+
+// wants reports whether there are subscribers to events of given kind.
+func (b *bus) wants(kind EventKind) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if sub.kinds&kind != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// publish delivers ev to the subscribers of its kind.
+func (b *bus) publish(ev WalkEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		if sub.kinds&ev.Kind != 0 {
+			sub.fn(ev)
+		}
+	}
+}
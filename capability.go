@@ -0,0 +1,65 @@
+package walks
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// Capability is a set of permissions of built-in actions run on behalf of a Walker, see Walker's Capabilities.
+// Capabilities are bit flags and can be combined with |.
+type Capability uint
+
+const (
+	// CanRead allows reading file contents (eg hashing, scanning).
+	CanRead Capability = 1 << iota
+	// CanWrite allows creating and modifying files and directories (eg temporary workspace, creating missing root, moving to trash).
+	CanWrite
+	// CanDelete allows removing files.
+	CanDelete
+	// CanExec allows running external programs and modules (eg exec hooks, WebAssembly actions).
+	CanExec
+
+	// AllCapabilities allows everything.
+	AllCapabilities Capability = 1<<iota - 1
+)
+
+// NoCapabilities allows nothing. Zero Capabilities grant everything, so a Walker denying all capabilities needs this explicit value;
+// combined with other capabilities (eg NoCapabilities|CanRead), it grants only those.
+const NoCapabilities Capability = 1 << 31
+
+// String returns the names of the capabilities in c separated by |, eg "read|write".
+func (c Capability) String() string {
+	var names []string
+	for i, name := range []string{"read", "write", "delete", "exec"} {
+		if c&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// ErrCapability is the reason of errors returned by built-in actions lacking a capability of their Walker.
+var ErrCapability = errors.New("walks: capability not granted")
+
+// Can reports whether w grants all capabilities in c.
+// Zero Capabilities grant everything, so that Walkers not configured otherwise are unrestricted; NoCapabilities grants nothing.
+func (w *Walker) Can(c Capability) bool {
+	return w.Capabilities == 0 || w.Capabilities&c == c
+}
+
+// Require returns an error for operation op on path (wrapping ErrCapability), unless w grants all capabilities in c.
+// Built-in actions call it before acting on behalf of a Walker: the ones with a Walker field (Remover, Quarantine, CAS, hook.Exec, WasmAction)
+// and the Walker methods of the package's reading and writing functions (eg CopyTree, ScanYara, Purge). These methods are permission gates only:
+// once the capabilities they need are granted, they call the package functions, that walk with package's Ignore, Search and DefaultFS,
+// not with the Walker's Ignore, Search, FS and Depth. Package functions themselves are not restricted.
+// User middleware and actions can use Require the same way, to constrain walk-driven plugins.
+func (w *Walker) Require(c Capability, op string, path string) error {
+	if w == nil || w.Can(c) {
+		return nil
+	}
+	return &os.PathError{Op: op, Path: path, Err: ErrCapability}
+}
@@ -0,0 +1,35 @@
+package walks
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	tests := []struct {
+		granted, c Capability
+		want       bool
+	}{
+		{0, CanRead | CanWrite | CanDelete | CanExec, true},
+		{AllCapabilities, CanExec, true},
+		{CanRead, CanRead, true},
+		{CanRead, CanRead | CanWrite, false},
+		{NoCapabilities, CanRead, false},
+		{NoCapabilities, CanExec, false},
+		{NoCapabilities | CanRead, CanRead, true},
+		{NoCapabilities | CanRead, CanDelete, false},
+	}
+	for _, tt := range tests {
+		w := New(WithCapabilities(tt.granted))
+		if got := w.Can(tt.c); got != tt.want {
+			t.Errorf("%v: Can(%v) = %v, want %v", tt.granted, tt.c, got, tt.want)
+		}
+	}
+	w := New(WithCapabilities(NoCapabilities))
+	if err := w.Require(CanRead, "read", "x"); !errors.Is(err, ErrCapability) {
+		t.Errorf("got error %v, want %v", err, ErrCapability)
+	}
+	if s := NoCapabilities.String(); s != "none" {
+		t.Errorf("got %v, want none", s)
+	}
+}
@@ -0,0 +1,98 @@
+package walks
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CAS is a Sink that ingests files into a content-addressable store: each file is stored once as objects/ab/cdef...,
+// where abcdef... is hex encoded SHA-256 of its contents.
+// For every put file a line "<sha256>  <path>" (same as sha256sum output) is written to the manifest, linking the original paths to the objects.
+// Like sha256sum does, paths containing backslashes or line breaks are escaped (as \\, \n and \r) and their lines prefixed with a backslash,
+// so that every line stays one record and the manifest can be checked with sha256sum -c.
+type CAS struct {
+	// Dir is the root directory of the store.
+	Dir string
+	// HardLink makes CAS hard link files into the store instead of copying, when source and store are on the same device.
+	// Files are copied when linking fails.
+	// Note that hard linked objects share contents with the sources, so later modification of a source also modifies the object.
+	HardLink bool
+	// Walker, if not nil, must grant CanRead and CanWrite for putting files into the store.
+	Walker *Walker
+
+	mu       sync.Mutex
+	manifest io.Writer
+}
+
+// NewCAS returns a CAS storing objects under dir and writing manifest lines to manifest.
+// Manifest can be nil, if it is not needed.
+func NewCAS(dir string, manifest io.Writer) (*CAS, error) {
+	if err := writable("cas", dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0755); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		manifest = io.Discard
+	}
+	return &CAS{Dir: dir, manifest: manifest}, nil
+}
+
+// Object returns the path of the object with hex encoded SHA-256 sum in the store.
+func (c *CAS) Object(sum string) string {
+	return filepath.Join(c.Dir, "objects", sum[:2], sum[2:])
+}
+
+// Put stores file at path, unless an object with the same contents is already in the store, and adds it to the manifest.
+func (c *CAS) Put(path string) error {
+	if err := c.Walker.Require(CanRead|CanWrite, "cas", path); err != nil {
+		return err
+	}
+	sum, err := fileSum(path)
+	if err != nil {
+		return err
+	}
+	obj := c.Object(sum)
+	if _, err := os.Stat(obj); os.IsNotExist(err) {
+		if err := c.store(path, obj); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = io.WriteString(c.manifest, manifestLine(sum, path))
+	return err
+}
+
+// manifestLine returns the manifest line of file path with sum, escaping path like sha256sum does when needed.
+func manifestLine(sum string, path string) string {
+	if !strings.ContainsAny(path, "\\\n\r") {
+		return sum + "  " + path + "\n"
+	}
+	escaped := strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\r", "\\r").Replace(path)
+	return "\\" + sum + "  " + escaped + "\n"
+}
+
+// store puts file at path into the store as object obj.
+func (c *CAS) store(path string, obj string) error {
+	if err := os.MkdirAll(filepath.Dir(obj), 0755); err != nil {
+		return err
+	}
+	if c.HardLink {
+		if err := os.Link(path, obj); err == nil || os.IsExist(err) {
+			return nil
+		}
+	}
+	return copyFile(path, obj)
+}
+
+// Close implements Sink, CAS has nothing to finish.
+func (c *CAS) Close() error {
+	return nil
+}
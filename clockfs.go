@@ -0,0 +1,149 @@
+package walks
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// Clock tells the current time. It is used for every time-based decision (eg in ColdData and Retention), so a fake clock makes them deterministic in tests.
+type Clock interface {
+	Now() time.Time
+}
+
+// FS is the filesystem walks reads walked trees from, so tests can substitute the real filesystem.
+// Paths use the same form as with package os. Symbolic links are read with the FS's Readlink method, if it has one (like OSFS does);
+// FSs without it have no links to read. Writes (creating, moving and removing files) and system information (mount tables, /proc, locks)
+// always use the operating system.
+type FS interface {
+	Open(name string) (fs.File, error)
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	// ReadDir returns the entries of directory name sorted by name, like os.ReadDir.
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// DefaultClock is the Clock used by package-level functions and by Walkers without their own Clock (see Walker's Clock).
+var DefaultClock Clock = SystemClock{}
+
+// DefaultFS is the FS used by package-level functions and by Walkers without their own FS.
+var DefaultFS FS = OSFS{}
+
+// SystemClock is a Clock telling the real time.
+type SystemClock struct{}
+
+// Now returns time.Now().
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+// FakeClock is a Clock, that only moves when told to. Zero value is a clock at zero time.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock returns a FakeClock set to now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Set sets the time of the clock.
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
+
+// Advance moves the clock forward by d.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// OSFS is the FS of the operating system.
+type OSFS struct{}
+
+// Open opens file name for reading, like os.Open.
+func (OSFS) Open(name string) (fs.File, error) {
+	return openRead(name)
+}
+
+// Stat returns os.Stat of name.
+func (OSFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+// Lstat returns os.Lstat of name.
+func (OSFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+// ReadDir returns os.ReadDir of name.
+func (OSFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+// Readlink returns os.Readlink of name.
+func (OSFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+// readLink returns the target of symbolic link name in fsys, see FS.
+func readLink(fsys FS, name string) (string, error) {
+	if l, ok := fsys.(interface {
+		Readlink(name string) (string, error)
+	}); ok {
+		return l.Readlink(name)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+}
+
+// readFile returns the contents of file name in fsys, like os.ReadFile.
+func readFile(fsys FS, name string) ([]byte, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
+}
+
+// readDir returns FileInfos of the entries of directory dir in fsys sorted by name, like ioutil.ReadDir.
+// Entries removed between listing the directory and reading their info are left out.
+func readDir(fsys FS, dir string) ([]os.FileInfo, error) {
+	return readDirVanished(fsys, dir, nil)
+}
+
+// readDirVanished is readDir, that calls vanished (if not nil) with the name of each entry left out because it was removed.
+func readDirVanished(fsys FS, dir string, vanished func(name string)) ([]os.FileInfo, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			if vanished != nil {
+				vanished(entry.Name())
+			}
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
@@ -0,0 +1,33 @@
+package walks
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// sysClonefileat is the number of clonefileat system call.
+	sysClonefileat = 462
+	// atFdcwd makes clonefileat resolve relative paths against current working directory.
+	atFdcwd = -2
+)
+
+// clone creates file dst as a copy-on-write clone of src using clonefile (APFS).
+// It fails, if the filesystem does not support cloning or src and dst are on different filesystems.
+func clone(src *os.File, dst string) error {
+	srcPtr, err := syscall.BytePtrFromString(src.Name())
+	if err != nil {
+		return err
+	}
+	dstPtr, err := syscall.BytePtrFromString(dst)
+	if err != nil {
+		return err
+	}
+	fd := atFdcwd
+	_, _, errno := syscall.Syscall6(sysClonefileat, uintptr(fd), uintptr(unsafe.Pointer(srcPtr)), uintptr(fd), uintptr(unsafe.Pointer(dstPtr)), 0, 0)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
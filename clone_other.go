@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package walks
+
+import (
+	"errors"
+	"os"
+)
+
+// clone is not supported on this platform, files are always copied.
+func clone(src *os.File, dst string) error {
+	return errors.New("walks: cloning files is not supported on this platform")
+}
@@ -0,0 +1,80 @@
+package walks
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ColdFile is a file, that has not been used for a while.
+type ColdFile struct {
+	Path     string
+	Size     int64
+	LastUsed time.Time
+	// FromMtime reports that LastUsed is the modification time, because access time was older (eg filesystem mounted with noatime) or not available.
+	FromMtime bool
+}
+
+// ColdDir aggregates cold files directly in one directory.
+type ColdDir struct {
+	Path  string
+	Files int
+	Bytes int64
+}
+
+// ColdReport is the result of ColdData.
+type ColdReport struct {
+	Files []ColdFile // cold files, sorted by path
+	Dirs  []ColdDir  // directories containing cold files, sorted by reclaimable bytes (largest first)
+	Bytes int64      // total size of cold files
+}
+
+// ColdData walks recursively given directory structure and reports regular files that have not been accessed for longer than age,
+// aggregated per directory with the bytes that archiving them would reclaim.
+// Last use of a file is its access time, or its modification time if that is newer or access time is not available;
+// the latter makes the report usable on filesystems mounted with noatime.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before ColdData call.
+func ColdData(root string, age time.Duration) (ColdReport, error) {
+	cutoff := DefaultClock.Now().Add(-age)
+	var report ColdReport
+	dirs := make(map[string]*ColdDir)
+	var firstErr error
+	fileAction := func(path string) {
+		info, err := DefaultFS.Stat(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		lastUsed, fromMtime := info.ModTime(), true
+		if atime, _, ok := fileTimes(info); ok && atime.After(lastUsed) {
+			lastUsed, fromMtime = atime, false
+		}
+		if !lastUsed.Before(cutoff) {
+			return
+		}
+		report.Files = append(report.Files, ColdFile{Path: path, Size: info.Size(), LastUsed: lastUsed, FromMtime: fromMtime})
+		report.Bytes += info.Size()
+		dir := filepath.Dir(path)
+		if dirs[dir] == nil {
+			dirs[dir] = &ColdDir{Path: dir}
+		}
+		dirs[dir].Files++
+		dirs[dir].Bytes += info.Size()
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	sort.Slice(report.Files, func(i, j int) bool { return report.Files[i].Path < report.Files[j].Path })
+	for _, dir := range dirs {
+		report.Dirs = append(report.Dirs, *dir)
+	}
+	sort.Slice(report.Dirs, func(i, j int) bool {
+		if report.Dirs[i].Bytes != report.Dirs[j].Bytes {
+			return report.Dirs[i].Bytes > report.Dirs[j].Bytes
+		}
+		return report.Dirs[i].Path < report.Dirs[j].Path
+	})
+	return report, firstErr
+}
@@ -0,0 +1,147 @@
+package walks
+
+import (
+	"compress/flate"
+	"io"
+	"path/filepath"
+)
+
+// CompressStats summarizes estimated compressibility of a group of files.
+type CompressStats struct {
+	Files      int
+	Bytes      int64 // total size of the files
+	Sampled    int64 // bytes read from the files for the estimate
+	Compressed int64 // size of the sampled bytes after compression
+}
+
+// Ratio returns the estimated compressed size relative to the original size, eg 0.3 means files shrink to 30%.
+// Ratio of a group without sampled bytes is 1.
+func (s CompressStats) Ratio() float64 {
+	if s.Sampled == 0 {
+		return 1
+	}
+	return float64(s.Compressed) / float64(s.Sampled)
+}
+
+// Saving returns the estimated number of bytes that compressing the files would save.
+func (s CompressStats) Saving() int64 {
+	return s.Bytes - int64(float64(s.Bytes)*s.Ratio())
+}
+
+// add adds stats of one file to s.
+func (s *CompressStats) add(size int64, sampled int64, compressed int64) {
+	s.Files++
+	s.Bytes += size
+	s.Sampled += sampled
+	s.Compressed += compressed
+}
+
+// CompressReport is the result of Compressibility, with files grouped by extension and by the directory they are in.
+type CompressReport struct {
+	ByExt map[string]*CompressStats
+	ByDir map[string]*CompressStats
+	Total CompressStats
+}
+
+// Compressibility walks recursively given directory structure and estimates how well files would compress,
+// by compressing a sample of up to sampleSize bytes (taken from the beginning and the middle) of every file with a fast deflate pass.
+// If sampleSize is not positive, 64 KiB is used.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Compressibility call.
+func Compressibility(root string, sampleSize int64) (CompressReport, error) {
+	if sampleSize <= 0 {
+		sampleSize = 64 * 1024
+	}
+	report := CompressReport{ByExt: make(map[string]*CompressStats), ByDir: make(map[string]*CompressStats)}
+	var firstErr error
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		size, sampled, compressed, err := compressSample(path, sampleSize)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		for _, group := range []struct {
+			m   map[string]*CompressStats
+			key string
+		}{{report.ByExt, filepath.Ext(path)}, {report.ByDir, filepath.Dir(path)}} {
+			if group.m[group.key] == nil {
+				group.m[group.key] = &CompressStats{}
+			}
+			group.m[group.key].add(size, sampled, compressed)
+		}
+		report.Total.add(size, sampled, compressed)
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return report, firstErr
+}
+
+// Compressibility samples files under root like package's Compressibility does, if w grants CanRead (see Require).
+func (w *Walker) Compressibility(root string, sampleSize int64) (CompressReport, error) {
+	if err := w.Require(CanRead, "compress", root); err != nil {
+		return CompressReport{}, err
+	}
+	return Compressibility(root, sampleSize)
+}
+
+// compressSample returns the size of file path, the number of sampled bytes and their compressed size.
+func compressSample(path string, sampleSize int64) (int64, int64, int64, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	size := info.Size()
+	var counter countWriter
+	zw, err := flate.NewWriter(&counter, flate.BestSpeed)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	var sampled int64
+	if size <= sampleSize {
+		sampled, err = io.Copy(zw, f)
+	} else {
+		half := sampleSize / 2
+		var n int64
+		if ra, ok := f.(io.ReaderAt); ok {
+			sampled, err = io.Copy(zw, io.NewSectionReader(ra, 0, half))
+			if err == nil {
+				n, err = io.Copy(zw, io.NewSectionReader(ra, size/2, sampleSize-half))
+			}
+		} else {
+			// files of FSs not reading at offsets are read through up to the second sample
+			sampled, err = io.Copy(zw, io.LimitReader(f, half))
+			if err == nil {
+				_, err = io.CopyN(io.Discard, f, size/2-half)
+			}
+			if err == nil {
+				n, err = io.Copy(zw, io.LimitReader(f, sampleSize-half))
+			}
+		}
+		sampled += n
+	}
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return 0, 0, 0, err
+	}
+	return size, sampled, counter.n, nil
+}
+
+// countWriter counts bytes written to it, discarding them.
+type countWriter struct {
+	n int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	w.n += int64(len(b))
+	return len(b), nil
+}
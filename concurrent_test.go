@@ -0,0 +1,225 @@
+package walks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// These tests share Walkers, DirCaches and entries between goroutines; run them with -race.
+
+func TestWalkerConcurrentWalks(t *testing.T) {
+	errBoom := errors.New("boom")
+	fsys := faultFS{FS: testTree(), readDirErr: map[string]error{"mem/d1/d2": errBoom}}
+	want := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/skip", "mem/skip/g.txt")
+	forModes(t, func(t *testing.T, ordered bool) {
+		// one Walker runs several walks at once, sharing its subscribers, cache and error policy
+		w := New(WithFS(fsys), WithOrdered(ordered), WithErrors(ErrorsCollect, nil), WithCache(NewDirCache()), WithCountBytes(true))
+		var events int64
+		w.Subscribe(EventAll, func(WalkEvent) { atomic.AddInt64(&events, 1) })
+		const walks = 8
+		var wg sync.WaitGroup
+		for i := 0; i < walks; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				got, err := walkPaths(w, "mem", nil)
+				var errs WalkErrors
+				if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs[0], errBoom) {
+					t.Errorf("got error %v, want WalkErrors with %v", err, errBoom)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got %v, want %v", got, want)
+				}
+				w.Stats()
+				w.Usage()
+			}()
+		}
+		wg.Wait()
+		// each walk publishes its 4 files, 3 directories and 4 finished directories (the root and the failed one included),
+		// but no EventError, as errors are collected
+		perWalk := int64(4 + 3 + 4)
+		if events != walks*perWalk {
+			t.Errorf("got %v events, want %v", events, walks*perWalk)
+		}
+		if stats := w.Stats(); stats.Files != 4 || stats.Dirs != 3 || stats.Errors != 1 {
+			t.Errorf("got stats %+v of the last walk, want 4 files, 3 directories and 1 error", stats)
+		}
+	})
+}
+
+func TestWalkerNestedShared(t *testing.T) {
+	files := fstest.MapFS{}
+	for path, file := range testFiles() {
+		files[path] = file
+		files["nested/"+path] = file
+	}
+	fsys := IOFS{FS: files}
+	// actions of the outer walk start nested walks, that share its limiter and need the workers it holds
+	w := New(WithFS(fsys), WithNested(NestedShared), WithMaxWorkers(2), WithQuota(Quota{MaxWorkers: 2}))
+	var nested int64
+	done := make(chan error, 1)
+	go func() {
+		_, err := walkPaths(w, "mem", func(e Entry) error {
+			if e.IsDir() {
+				return nil
+			}
+			got, err := walkPaths(w, "nested/mem", nil)
+			if err == nil && len(got) != len(allTestPaths) {
+				t.Errorf("nested walk got %v paths, want %v", len(got), len(allTestPaths))
+			}
+			atomic.AddInt64(&nested, 1)
+			return err
+		})
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("nested walks did not finish")
+	}
+	if nested != 6 {
+		t.Errorf("got %v nested walks, want 6", nested)
+	}
+	if usage := w.Usage(); usage.PeakWorkers > 2 {
+		t.Errorf("got %v workers at once, want at most 2", usage.PeakWorkers)
+	}
+}
+
+func TestDirCacheShared(t *testing.T) {
+	cache := NewDirCache()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(ordered bool) {
+			defer wg.Done()
+			// each walk has its own Walker, sharing the cache
+			w := New(WithFS(testTree()), WithCache(cache), WithOrdered(ordered))
+			got, err := walkPaths(w, "mem", nil)
+			if err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(got, allTestPaths) {
+				t.Errorf("got %v, want %v", got, allTestPaths)
+			}
+		}(i%2 == 0)
+	}
+	wg.Wait()
+	// the cached tree has 5 directories: all listings after the first walk to read them are hits
+	if hits, misses := cache.Stats(); hits+misses != 8*5 || misses < 5 {
+		t.Errorf("got %v hits and %v misses, want 40 listings with at least 5 misses", hits, misses)
+	}
+	cache.Clear()
+	if hits, misses := cache.Stats(); hits != 0 || misses != 0 {
+		t.Errorf("got %v hits and %v misses after Clear, want none", hits, misses)
+	}
+}
+
+func TestEntryCloneShared(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// cloned entries are used by other goroutines after the actions returned and the walk finished
+		entries := make(chan Entry, len(allTestPaths))
+		w := New(WithFS(testTree()), WithOrdered(ordered))
+		if _, err := walkPaths(w, "mem", func(e Entry) error {
+			entries <- e.Clone()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		close(entries)
+		var wg sync.WaitGroup
+		for e := range entries {
+			wg.Add(1)
+			go func(e Entry) {
+				defer wg.Done()
+				info, err := e.Info()
+				if err != nil {
+					t.Errorf("%v: %v", e.Path, err)
+					return
+				}
+				if info.Name() != e.Name() || info.IsDir() != e.IsDir() {
+					t.Errorf("%v: info of %v (directory %v) does not match the entry", e.Path, info.Name(), info.IsDir())
+				}
+			}(e)
+		}
+		wg.Wait()
+	})
+}
+
+func TestWalkerCancelConcurrent(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// cancelling one of the concurrent walks of a Walker does not affect the others
+		w := New(WithFS(testTree()), WithOrdered(ordered))
+		ctx, cancel := context.WithCancel(context.Background())
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := walkPaths(w, "mem", nil); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		action := func(e Entry) error {
+			cancel()
+			return nil
+		}
+		if err := w.WalkEntries(ctx, "mem", action, action); err != context.Canceled {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+		wg.Wait()
+	})
+}
+
+func TestWalkerRetainedEntries(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		root := t.TempDir()
+		for _, name := range []string{"a", "b", "c"} {
+			if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// retained entries stored by the actions keep describing the files as walked, after the files are removed
+		var mu sync.Mutex
+		var kept []Entry
+		w := New(WithOrdered(ordered), WithRetainedEntries(true))
+		if _, err := walkPaths(w, root, func(e Entry) error {
+			mu.Lock()
+			kept = append(kept, e)
+			mu.Unlock()
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(root); err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		for _, e := range kept {
+			wg.Add(1)
+			go func(e Entry) {
+				defer wg.Done()
+				if info, err := e.Info(); err != nil {
+					t.Errorf("%v: %v", e.Path, err)
+				} else if info.Size() != 1 {
+					t.Errorf("%v: got size %v, want 1", e.Path, info.Size())
+				}
+			}(e)
+		}
+		wg.Wait()
+		if len(kept) != 3 {
+			t.Errorf("got %v entries, want 3", len(kept))
+		}
+	})
+}
@@ -0,0 +1,110 @@
+/*
+Package contenttype classifies walked files by content type, detected from their names or contents.
+
+It is a package of its own, so that programs not classifying files do not link package net/http (of its content sniffing).
+*/
+package contenttype
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/moledoc/walks"
+)
+
+// Mode selects how Count detects the type of a file, trading accuracy for speed.
+type Mode int
+
+const (
+	// ByExtension detects content type from the file name extension only (see mime.TypeByExtension), reading no file contents.
+	ByExtension Mode = iota
+	// ByMagic detects content type from the first 512 bytes of each file (see http.DetectContentType), regardless of its name.
+	ByMagic
+	// ByExtensionOrMagic detects content type from the extension, reading the file only when the extension is unknown.
+	ByExtensionOrMagic
+)
+
+// unknownContent is the content type of files, whose type could not be detected.
+const unknownContent = "application/octet-stream"
+
+// Stats aggregates the files of one content type.
+type Stats struct {
+	Type  string // MIME type without parameters, eg "image/png"
+	Files int
+	Bytes int64
+}
+
+// Count walks recursively given directory structure and reports the number and total size of regular files by content type,
+// sorted by bytes (largest first), eg for storage classification or to see what a migration involves.
+// Files whose type cannot be detected (or that cannot be read, when their contents are needed) are reported as application/octet-stream.
+// When mode reads file contents, they are not read unless Walker w grants CanRead (see walks' Require); w may be nil.
+// Directories and files can be ignored by setting package walks' Ignore value with SetIgnore function or manually before Count call.
+func Count(w *walks.Walker, root string, mode Mode) ([]Stats, error) {
+	if mode != ByExtension {
+		if err := w.Require(walks.CanRead, "sniff", root); err != nil {
+			return nil, err
+		}
+	}
+	types := make(map[string]*Stats)
+	var firstErr error
+	fileAction := func(path string) {
+		info, err := walks.DefaultFS.Lstat(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		typ := ""
+		if mode != ByMagic {
+			typ = mime.TypeByExtension(filepath.Ext(path))
+		}
+		if typ == "" && mode != ByExtension {
+			typ = sniff(path)
+		}
+		if i := strings.IndexByte(typ, ';'); i >= 0 {
+			typ = strings.TrimSpace(typ[:i])
+		}
+		if typ == "" {
+			typ = unknownContent
+		}
+		if types[typ] == nil {
+			types[typ] = &Stats{Type: typ}
+		}
+		types[typ].Files++
+		types[typ].Bytes += info.Size()
+	}
+	if _, err := walks.WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	stats := make([]Stats, 0, len(types))
+	for _, s := range types {
+		stats = append(stats, *s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Bytes != stats[j].Bytes {
+			return stats[i].Bytes > stats[j].Bytes
+		}
+		return stats[i].Type < stats[j].Type
+	})
+	return stats, firstErr
+}
+
+// sniff returns the content type of file path detected from its first bytes, or "" if it cannot be read.
+func sniff(path string) string {
+	f, err := walks.DefaultFS.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return ""
+	}
+	return http.DetectContentType(head[:n])
+}
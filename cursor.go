@@ -0,0 +1,121 @@
+package walks
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// Cursor pages through a directory structure in lexical depth-first order, a page of entries at a time.
+// Each page comes with an opaque resume token, which can be given to NewCursor to continue from the same position later (eg in the next request of a web UI),
+// so that very large listings never need to be held in memory.
+type Cursor struct {
+	root  string
+	stack []cursorDir
+}
+
+// cursorDir is the listing of a directory in Cursor's stack, with next being the index of the next entry to visit.
+type cursorDir struct {
+	path    string
+	depth   int
+	entries []fs.DirEntry
+	next    int
+}
+
+// NewCursor returns a Cursor over directory root, positioned after the entry the token was returned for.
+// Empty token starts from the beginning.
+// Entries added or removed between the pages are handled gracefully: the cursor continues from the next entry in lexical order.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before NewCursor call.
+func NewCursor(root string, token string) (*Cursor, error) {
+	c := &Cursor{root: root}
+	if err := c.push(root, 0); err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return c, nil
+	}
+	rel, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	elems := strings.Split(string(rel), string(filepath.Separator))
+	for _, elem := range elems {
+		top := &c.stack[len(c.stack)-1]
+		for top.next < len(top.entries) && top.entries[top.next].Name() < elem {
+			top.next++
+		}
+		if top.next == len(top.entries) || top.entries[top.next].Name() != elem {
+			// resume entry no longer exists, continue from the next one.
+			break
+		}
+		info := top.entries[top.next]
+		top.next++
+		if !info.IsDir() {
+			break
+		}
+		if err := c.push(filepath.Join(top.path, elem), top.depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// push adds the listing of directory path to the stack.
+func (c *Cursor) push(path string, depth int) error {
+	entries, err := DefaultFS.ReadDir(path)
+	if err != nil {
+		return err
+	}
+	c.stack = append(c.stack, cursorDir{path: path, depth: depth, entries: entries})
+	return nil
+}
+
+// Next returns up to n next entries and a token to resume after the last of them.
+// Returned token is empty when there are no more entries; the last page may contain fewer than n entries or be empty.
+// n must be positive.
+func (c *Cursor) Next(n int) ([]Entry, string, error) {
+	if n <= 0 {
+		return nil, "", fmt.Errorf("walks: invalid page size %v", n)
+	}
+	var page []Entry
+	for len(page) < n && len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.next == len(top.entries) {
+			c.stack = c.stack[:len(c.stack)-1]
+			continue
+		}
+		info := top.entries[top.next]
+		top.next++
+		pathName := filepath.Join(top.path, info.Name())
+		if ignoredPath(pathName, info.IsDir()) {
+			continue
+		}
+		page = append(page, Entry{Path: pathName, Depth: top.depth, d: info})
+		if info.IsDir() {
+			if err := c.push(pathName, top.depth+1); err != nil {
+				return page, c.token(page), err
+			}
+		}
+	}
+	for len(c.stack) > 0 && c.stack[len(c.stack)-1].next == len(c.stack[len(c.stack)-1].entries) {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	if len(c.stack) == 0 {
+		return page, "", nil
+	}
+	return page, c.token(page), nil
+}
+
+// token returns the resume token for the last entry of page.
+func (c *Cursor) token(page []Entry) string {
+	if len(page) == 0 {
+		return ""
+	}
+	rel, err := filepath.Rel(c.root, page[len(page)-1].Path)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString([]byte(rel))
+}
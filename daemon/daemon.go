@@ -0,0 +1,281 @@
+/*
+Package daemon keeps snapshots of walked roots warm and answers queries about them over HTTP.
+
+It is a package of its own, so that programs not serving indexes do not link package net/http.
+*/
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/moledoc/walks"
+)
+
+// Daemon keeps snapshots of configured roots warm, refreshing them incrementally, and answers queries about them over HTTP,
+// turning walks into an embeddable file-index service.
+//
+// Endpoints (all respond with JSON):
+//
+//	GET /search?q=<regexp>[&limit=<n>]  paths matching regexp
+//	GET /du?path=<path>[&deleted=1]     total size of regular files under path (with deleted=1 also of deleted files still held open, on Linux)
+//	GET /diff?root=<root>&since=<time>  changes of root since time (RFC 3339), as far as History reaches
+type Daemon struct {
+	// Roots are the directories kept in the index.
+	Roots []string
+	// Interval is the time between refreshes of the snapshots; 0 (or negative) means DefaultInterval.
+	Interval time.Duration
+	// History is the number of past snapshots kept per root for diff queries; 0 means 10.
+	History int
+	// Rules are checked after each refresh against the previous snapshot of the root, calling Alert with every triggered rule (see walks.Growth).
+	Rules []walks.GrowthRule
+	// Alert is called with alerts of Rules; nil disables the checks.
+	Alert func(walks.GrowthAlert)
+
+	mu    sync.RWMutex
+	snaps map[string][]*walks.Snapshot // per root, oldest first
+	index map[string]*index            // of the newest snapshot per root
+}
+
+// DefaultInterval is the time between refreshes of Daemon's snapshots, when its Interval is not set.
+const DefaultInterval = time.Minute
+
+// index is the query index of a snapshot, built once per refresh.
+type index struct {
+	snap  *walks.Snapshot
+	paths []string         // sorted paths of the snapshot
+	usage map[string]int64 // total size of regular files under each recorded path having any
+}
+
+// newIndex builds the index of snap.
+func newIndex(snap *walks.Snapshot) *index {
+	idx := &index{snap: snap, paths: snap.Paths(), usage: make(map[string]int64)}
+	for path, entry := range snap.Entries {
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		for p := path; ; {
+			idx.usage[p] += entry.Size
+			parent := filepath.Dir(p)
+			if _, ok := snap.Entries[parent]; !ok || parent == p {
+				break
+			}
+			p = parent
+		}
+	}
+	return idx
+}
+
+// New returns a Daemon indexing roots, refreshing them every interval.
+func New(roots []string, interval time.Duration) *Daemon {
+	return &Daemon{Roots: roots, Interval: interval}
+}
+
+// Run takes the initial snapshots and keeps refreshing them until ctx is done.
+// Errors of individual refreshes are not fatal: the previous snapshot stays in use until the next refresh.
+func (d *Daemon) Run(ctx context.Context) error {
+	d.refresh()
+	interval := d.Interval
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.refresh()
+		}
+	}
+}
+
+// refresh refreshes the snapshots of all roots.
+func (d *Daemon) refresh() {
+	history := d.History
+	if history <= 0 {
+		history = 10
+	}
+	for _, root := range d.Roots {
+		var snap *walks.Snapshot
+		var err error
+		last := d.latest(root)
+		if last != nil {
+			snap, err = last.Refresh()
+		} else {
+			snap, err = walks.TakeSnapshot(root)
+		}
+		if err != nil {
+			continue
+		}
+		if last != nil && d.Alert != nil {
+			for _, alert := range walks.Growth(last, snap, d.Rules) {
+				d.Alert(alert)
+			}
+		}
+		idx := newIndex(snap)
+		d.mu.Lock()
+		if d.snaps == nil {
+			d.snaps = make(map[string][]*walks.Snapshot)
+			d.index = make(map[string]*index)
+		}
+		d.index[root] = idx
+		snaps := append(d.snaps[root], snap)
+		if len(snaps) > history {
+			snaps = snaps[len(snaps)-history:]
+		}
+		d.snaps[root] = snaps
+		d.mu.Unlock()
+	}
+}
+
+// latest returns the newest snapshot of root, or nil if there is none.
+func (d *Daemon) latest(root string) *walks.Snapshot {
+	if idx := d.indexOf(root); idx != nil {
+		return idx.snap
+	}
+	return nil
+}
+
+// indexOf returns the index of the newest snapshot of root, or nil if there is none.
+func (d *Daemon) indexOf(root string) *index {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.index[root]
+}
+
+// Handler returns the HTTP handler serving the query endpoints.
+func (d *Daemon) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search", d.search)
+	mux.HandleFunc("/du", d.du)
+	mux.HandleFunc("/diff", d.diff)
+	return mux
+}
+
+// ListenAndServe serves Handler on a listener for network and addr (eg "unix" and a socket path, or "tcp" and "localhost:8080") until ctx is done.
+// Stale unix socket file at addr is removed before listening; other files at addr are left alone, failing the listen.
+func (d *Daemon) ListenAndServe(ctx context.Context, network string, addr string) error {
+	if network == "unix" {
+		if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+			if walks.StrictReadOnly {
+				return &os.PathError{Op: "remove", Path: addr, Err: walks.ErrReadOnly}
+			}
+			if err := os.Remove(addr); err != nil {
+				return err
+			}
+		}
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: d.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Shutdown(context.Background())
+	}()
+	if err := srv.Serve(ln); err != http.ErrServerClosed {
+		return err
+	}
+	return ctx.Err()
+}
+
+// search serves paths matching the regexp in query parameter q.
+func (d *Daemon) search(rw http.ResponseWriter, r *http.Request) {
+	re, err := regexp.Compile(r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := -1
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	matches := []string{}
+	for _, root := range d.Roots {
+		idx := d.indexOf(root)
+		if idx == nil {
+			continue
+		}
+		for _, path := range idx.paths {
+			if limit >= 0 && len(matches) >= limit {
+				break
+			}
+			if re.MatchString(path) {
+				matches = append(matches, path)
+			}
+		}
+	}
+	writeJSON(rw, matches)
+}
+
+// du serves the total size of regular files under query parameter path.
+func (d *Daemon) du(rw http.ResponseWriter, r *http.Request) {
+	path := r.URL.Query().Get("path")
+	for _, root := range d.Roots {
+		idx := d.indexOf(root)
+		if idx == nil {
+			continue
+		}
+		if _, ok := idx.snap.Entries[path]; ok {
+			resp := map[string]interface{}{"path": path, "bytes": idx.usage[path]}
+			if r.URL.Query().Get("deleted") == "1" {
+				if deleted, _, err := walks.DeletedUsage(path); err == nil {
+					resp["deleted_open_bytes"] = deleted
+				}
+			}
+			writeJSON(rw, resp)
+			return
+		}
+	}
+	http.Error(rw, "path not indexed", http.StatusNotFound)
+}
+
+// diff serves the changes of query parameter root since query parameter since.
+func (d *Daemon) diff(rw http.ResponseWriter, r *http.Request) {
+	root := r.URL.Query().Get("root")
+	since, err := time.Parse(time.RFC3339, r.URL.Query().Get("since"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	d.mu.RLock()
+	snaps := d.snaps[root]
+	d.mu.RUnlock()
+	if len(snaps) == 0 {
+		http.Error(rw, "root not indexed", http.StatusNotFound)
+		return
+	}
+	// base is the newest snapshot taken at or before since, or the oldest one kept.
+	base := snaps[0]
+	for _, snap := range snaps {
+		if snap.Taken.After(since) {
+			break
+		}
+		base = snap
+	}
+	events := base.Diff(snaps[len(snaps)-1])
+	if events == nil {
+		events = []walks.Event{}
+	}
+	writeJSON(rw, map[string]interface{}{"root": root, "since": base.Taken, "changes": events})
+}
+
+// writeJSON writes v as JSON response.
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(v)
+}
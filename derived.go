@@ -0,0 +1,150 @@
+package walks
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Freshness selects how WriteDerived decides that a derived file is up to date with its source.
+type Freshness int
+
+const (
+	// FreshMtime considers derived file up to date, when it is not older than its source.
+	FreshMtime Freshness = iota
+	// FreshHash considers derived file up to date, when SHA-256 of the source matches the one recorded when the derived file was written.
+	// The hash is recorded in a sidecar file next to the derived file, named with suffix SumSuffix.
+	FreshHash
+)
+
+// SumSuffix is the suffix of sidecar files, where FreshHash records the hash of the source.
+const SumSuffix = ".srcsum"
+
+// WriteDerived writes file dst derived from file src (eg a thumbnail or converted document), unless dst is already up to date according to fresh.
+// Contents of dst are produced by produce, that is given src and a writer to write the output to.
+// The output is written atomically: either the whole output replaces dst or dst is left untouched.
+// Returned bool reports whether dst was (re)written.
+func WriteDerived(src string, dst string, fresh Freshness, produce func(src string, w io.Writer) error) (bool, error) {
+	if err := writable("write", dst); err != nil {
+		return false, err
+	}
+	srcInfo, err := os.Stat(src)
+	if err != nil {
+		return false, err
+	}
+	var sum string
+	switch fresh {
+	case FreshMtime:
+		if dstInfo, err := os.Stat(dst); err == nil && !dstInfo.ModTime().Before(srcInfo.ModTime()) {
+			return false, nil
+		}
+	case FreshHash:
+		if sum, err = fileSum(src); err != nil {
+			return false, err
+		}
+		recorded, err := os.ReadFile(dst + SumSuffix)
+		if err == nil && string(bytes.TrimSpace(recorded)) == sum {
+			if _, err := os.Stat(dst); err == nil {
+				return false, nil
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, err
+	}
+	err = WriteFileAtomic(dst, 0644, func(w io.Writer) error { return produce(src, w) })
+	if err != nil {
+		return false, err
+	}
+	if fresh == FreshHash {
+		if err := os.WriteFile(dst+SumSuffix, []byte(sum+"\n"), 0644); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// WriteDerived is package's WriteDerived allowed only if w grants CanRead and CanWrite (see Require).
+func (w *Walker) WriteDerived(src string, dst string, fresh Freshness, produce func(src string, w io.Writer) error) (bool, error) {
+	if err := w.Require(CanRead|CanWrite, "write", dst); err != nil {
+		return false, err
+	}
+	return WriteDerived(src, dst, fresh, produce)
+}
+
+// DerivedPath returns the path of a file derived from src, mirrored from srcRoot to dstRoot and with extension replaced by ext.
+// If dstRoot is empty, derived file is placed next to the source.
+func DerivedPath(src string, srcRoot string, dstRoot string, ext string) (string, error) {
+	out := strings.TrimSuffix(src, filepath.Ext(src)) + ext
+	if dstRoot == "" {
+		return out, nil
+	}
+	rel, err := filepath.Rel(srcRoot, out)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dstRoot, rel), nil
+}
+
+// WriteFileAtomic writes the output of produce to path with permissions perm.
+// Output is written to a temporary file in the same directory, that is renamed to path once produce has succeeded.
+func WriteFileAtomic(path string, perm os.FileMode, produce func(w io.Writer) error) error {
+	if err := writable("write", path); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op after successful rename
+	if err := produce(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// WriteFileAtomic is package's WriteFileAtomic allowed only if w grants CanWrite (see Require).
+func (w *Walker) WriteFileAtomic(path string, perm os.FileMode, produce func(w io.Writer) error) error {
+	if err := w.Require(CanWrite, "write", path); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, perm, produce)
+}
+
+// fileSum returns hex encoded SHA-256 of the contents of file path.
+func fileSum(path string) (string, error) {
+	sum, err := hashFile(path)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// hashFile returns SHA-256 of the contents of file path.
+func hashFile(path string) ([]byte, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
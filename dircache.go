@@ -0,0 +1,165 @@
+package walks
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// DirCache is an opportunistic cache of directory listings for repeated walks of the same trees (see Walker's Cache).
+// A cached listing is reused instead of reading the directory again, while the directory's modification time, size,
+// inode and link count (which grows with subdirectories on most Unix filesystems) are unchanged since it was read.
+//
+// Caveats: a directory changed without its modification time changing is not noticed. That happens when its mtime is reset
+// (eg by tar, rsync or touch), on filesystems with coarse timestamps when a change follows the listing within the same tick
+// (listings of directories modified in the last RacyWindow are not cached for that reason), and on network filesystems caching attributes.
+// Only listings are cached: entries' info (sizes, modes, times) is read anew by each walk. Set Revalidate to read every directory again
+// for walks, that must be exact, while still refreshing the cache for later ones.
+// DirCache is safe for concurrent use and can be shared by Walkers.
+type DirCache struct {
+	// Revalidate makes walks read every directory instead of using cached listings; what they read is still cached.
+	Revalidate bool
+	// RacyWindow is the age of a directory's modification time, under which its listing is not cached; 0 means 2 seconds.
+	RacyWindow time.Duration
+
+	mu       sync.Mutex
+	listings map[string]cachedListing
+	hits     int
+	misses   int
+}
+
+// cachedListing is a listing of a directory with the state of the directory when it was read.
+type cachedListing struct {
+	key     dirKey
+	entries []cachedEntry
+}
+
+// cachedEntry is an entry of a cached listing. Its info is read (with lstat) on each walk, so that it is never stale.
+type cachedEntry struct {
+	fsys FS
+	path string
+	name string
+	typ  fs.FileMode
+}
+
+// Name returns the base name of the entry.
+func (e cachedEntry) Name() string {
+	return e.name
+}
+
+// IsDir reports whether the entry is a directory.
+func (e cachedEntry) IsDir() bool {
+	return e.typ.IsDir()
+}
+
+// Type returns the type bits of the entry.
+func (e cachedEntry) Type() fs.FileMode {
+	return e.typ
+}
+
+// Info returns the current info of the entry.
+func (e cachedEntry) Info() (fs.FileInfo, error) {
+	return e.fsys.Lstat(e.path)
+}
+
+// dirKey is the state of a directory, that its cached listing is valid for.
+type dirKey struct {
+	modTime  time.Time
+	size     int64
+	dev, ino uint64
+	links    uint64
+}
+
+// NewDirCache returns an empty DirCache.
+func NewDirCache() *DirCache {
+	return &DirCache{listings: make(map[string]cachedListing)}
+}
+
+// Forget removes the cached listing of directory dir, if any.
+func (c *DirCache) Forget(dir string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.listings, dir)
+}
+
+// Clear removes all cached listings.
+func (c *DirCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listings = make(map[string]cachedListing)
+	c.hits, c.misses = 0, 0
+}
+
+// Stats returns the number of cache hits (reused listings) and misses (directories read) since the cache was created or cleared.
+func (c *DirCache) Stats() (hits int, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// newDirKey returns the key of directory described by info.
+func newDirKey(info os.FileInfo) dirKey {
+	k := dirKey{modTime: info.ModTime(), size: info.Size()}
+	k.dev, k.ino, _ = fileID(info)
+	k.links, _ = fileLinks(info)
+	return k
+}
+
+// readDir returns the entries of directory dir in fsys sorted by name, reusing the cached listing if dir has not changed since it was read.
+func (c *DirCache) readDir(fsys FS, dir string) ([]fs.DirEntry, error) {
+	info, err := fsys.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	key := newDirKey(info)
+	c.mu.Lock()
+	if c.listings == nil {
+		c.listings = make(map[string]cachedListing)
+	}
+	cached, ok := c.listings[dir]
+	if ok && !c.Revalidate && cached.key == key {
+		c.hits++
+		c.mu.Unlock()
+		entries := make([]fs.DirEntry, len(cached.entries))
+		for i, e := range cached.entries {
+			entries[i] = e
+		}
+		return entries, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		c.Forget(dir)
+		return nil, err
+	}
+	racy := c.RacyWindow
+	if racy <= 0 {
+		racy = 2 * time.Second
+	}
+	if DefaultClock.Now().Sub(key.modTime) < racy {
+		c.Forget(dir)
+		return entries, nil
+	}
+	cached = cachedListing{key: key, entries: make([]cachedEntry, len(entries))}
+	for i, e := range entries {
+		cached.entries[i] = cachedEntry{fsys: fsys, path: joinPath(dir, e.Name()), name: e.Name(), typ: e.Type()}
+	}
+	c.mu.Lock()
+	c.listings[dir] = cached
+	c.mu.Unlock()
+	return entries, nil
+}
+
+// readDir returns the entries of directory dir sorted by name, from Walker's Cache if set.
+func (w *Walker) readDir(dir string) ([]fs.DirEntry, error) {
+	v, err := w.timed("readdir", dir, func() (interface{}, error) {
+		if w.Cache == nil {
+			return w.fsys().ReadDir(dir)
+		}
+		return w.Cache.readDir(w.fsys(), dir)
+	}, nil)
+	entries, _ := v.([]fs.DirEntry)
+	return entries, err
+}
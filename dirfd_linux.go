@@ -0,0 +1,27 @@
+package walks
+
+import (
+	"os"
+	"syscall"
+)
+
+// openDirAt opens subdirectory name of open directory parent relative to parent's file descriptor,
+// without resolving the full path again and without following a symlink.
+// parent's file descriptor is held until openat returns, so that parent can be closed meanwhile (eg after the open timed out, see OpTimeout).
+func openDirAt(parent *os.File, name string) (*os.File, error) {
+	path := parent.Name() + "/" + name
+	conn, err := parent.SyscallConn()
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: path, Err: err}
+	}
+	var fd int
+	if cerr := conn.Control(func(pfd uintptr) {
+		fd, err = syscall.Openat(int(pfd), name, syscall.O_RDONLY|syscall.O_DIRECTORY|syscall.O_NOFOLLOW|syscall.O_CLOEXEC, 0)
+	}); cerr != nil {
+		return nil, &os.PathError{Op: "openat", Path: path, Err: cerr}
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: path, Err: err}
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+import "os"
+
+// openDirAt opens subdirectory name of open directory parent.
+// openat is not used on this platform, the subdirectory is opened by its full path.
+func openDirAt(parent *os.File, name string) (*os.File, error) {
+	return os.Open(joinPath(parent.Name(), name))
+}
@@ -0,0 +1,199 @@
+package walks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// FindDuplicates walks recursively given directory structure and returns groups of regular files with identical contents.
+// Files are first grouped by size and only files with equal sizes are hashed; empty files are not reported.
+// Paths in each group, and the groups by their first path, are sorted.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before FindDuplicates call.
+func FindDuplicates(root string) ([][]string, error) {
+	bySize := make(map[int64][]string)
+	var firstErr error
+	fileAction := func(path string) {
+		info, err := DefaultFS.Stat(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		if info.Size() > 0 {
+			bySize[info.Size()] = append(bySize[info.Size()], path)
+		}
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	var groups [][]string
+	for _, paths := range bySize {
+		if len(paths) < 2 {
+			continue
+		}
+		bySum := make(map[string][]string)
+		for _, path := range paths {
+			sum, err := fileSum(path)
+			if err != nil {
+				return nil, err
+			}
+			bySum[sum] = append(bySum[sum], path)
+		}
+		for _, group := range bySum {
+			if len(group) > 1 {
+				sort.Strings(group)
+				groups = append(groups, group)
+			}
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups, nil
+}
+
+// FindDuplicates finds duplicate files under root like package's FindDuplicates does, if w grants CanRead to hash them (see Require).
+func (w *Walker) FindDuplicates(root string) ([][]string, error) {
+	if err := w.Require(CanRead, "hash", root); err != nil {
+		return nil, err
+	}
+	return FindDuplicates(root)
+}
+
+// ConsolidateOptions configures Consolidate.
+type ConsolidateOptions struct {
+	// Symlink replaces duplicates with symbolic links instead of hard links.
+	Symlink bool
+	// SamePerm only consolidates duplicates, that have the same permissions as the kept file,
+	// since hard links share permissions and replacing a file with a link to another may change its effective permissions.
+	SamePerm bool
+	// DryRun only reports what would be done.
+	DryRun bool
+}
+
+// ConsolidateReport is the result of Consolidate.
+type ConsolidateReport struct {
+	Linked    []string          // duplicates replaced with links (or that would be, in dry run)
+	Skipped   map[string]string // duplicates left alone, with the reason
+	Reclaimed int64             // bytes reclaimed by the replaced duplicates; a file with other hard links counts once all of them are replaced
+}
+
+// Consolidate replaces duplicates in each group (eg returned by FindDuplicates) with links to the first file of the group.
+// Before replacing, each file is verified to still have the same contents as the kept file; hard links are only made within the same device.
+// Files that are already hard links to the kept file are skipped.
+// The replacement is atomic: link is created under a temporary name and renamed over the duplicate.
+func Consolidate(groups [][]string, opts ConsolidateOptions) (ConsolidateReport, error) {
+	report := ConsolidateReport{Skipped: make(map[string]string)}
+	replaced := make(map[[2]uint64]uint64)
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		keep := group[0]
+		keepInfo, err := os.Stat(keep)
+		if err != nil {
+			return report, err
+		}
+		keepSum, err := fileSum(keep)
+		if err != nil {
+			return report, err
+		}
+		keepDev, _, keepIDOk := fileID(keepInfo)
+		for _, dup := range group[1:] {
+			info, err := os.Lstat(dup)
+			if err != nil {
+				return report, err
+			}
+			if reason := consolidateCheck(keepInfo, keepSum, keepDev, keepIDOk, dup, info, opts); reason != "" {
+				report.Skipped[dup] = reason
+				continue
+			}
+			if !opts.DryRun {
+				if err := replaceWithLink(keep, dup, opts.Symlink); err != nil {
+					return report, err
+				}
+			}
+			report.Linked = append(report.Linked, dup)
+			report.Reclaimed += reclaimed(info, replaced)
+		}
+	}
+	return report, nil
+}
+
+// reclaimed returns the bytes reclaimed by replacing duplicate described by info: its size, unless it has other hard links,
+// that have not been replaced yet. replaced counts the replaced links of each file with more of them.
+func reclaimed(info os.FileInfo, replaced map[[2]uint64]uint64) int64 {
+	links, ok := fileLinks(info)
+	dev, ino, idOk := fileID(info)
+	if !ok || !idOk || links <= 1 {
+		return info.Size()
+	}
+	id := [2]uint64{dev, ino}
+	if replaced[id]++; replaced[id] < links {
+		return 0
+	}
+	return info.Size()
+}
+
+// Consolidate replaces duplicates with links like package's Consolidate does, if w grants CanRead, CanWrite and CanDelete (see Require).
+func (w *Walker) Consolidate(groups [][]string, opts ConsolidateOptions) (ConsolidateReport, error) {
+	for _, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		if err := w.Require(CanRead|CanWrite|CanDelete, "link", group[0]); err != nil {
+			return ConsolidateReport{Skipped: make(map[string]string)}, err
+		}
+	}
+	return Consolidate(groups, opts)
+}
+
+// consolidateCheck returns the reason why dup cannot be replaced with link to the kept file, or empty string if it can.
+func consolidateCheck(keepInfo os.FileInfo, keepSum string, keepDev uint64, keepIDOk bool, dup string, info os.FileInfo, opts ConsolidateOptions) string {
+	if !info.Mode().IsRegular() {
+		return "not a regular file"
+	}
+	if os.SameFile(keepInfo, info) {
+		return "already linked"
+	}
+	if opts.SamePerm && info.Mode().Perm() != keepInfo.Mode().Perm() {
+		return "different permissions"
+	}
+	if dev, _, ok := fileID(info); !opts.Symlink && ok && keepIDOk && dev != keepDev {
+		return "different device"
+	}
+	if sum, err := fileSum(dup); err != nil || sum != keepSum {
+		return "contents changed"
+	}
+	return ""
+}
+
+// replaceWithLink atomically replaces dup with a hard or symbolic link to keep.
+func replaceWithLink(keep string, dup string, symlink bool) error {
+	if err := writable("link", dup); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(dup), fmt.Sprintf(".%v.link-%v", filepath.Base(dup), os.Getpid()))
+	var err error
+	if symlink {
+		target := keep
+		if abs, absErr := filepath.Abs(keep); absErr == nil {
+			target = abs
+		}
+		err = os.Symlink(target, tmp)
+	} else {
+		err = os.Link(keep, tmp)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dup); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
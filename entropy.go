@@ -0,0 +1,198 @@
+package walks
+
+import (
+	"bytes"
+	"io"
+	"math"
+)
+
+// DataClass classifies the contents of a file for security inventories, see Classify.
+type DataClass int
+
+const (
+	// DataPlain is data, that is neither a known compressed or encrypted format, nor random-looking.
+	DataPlain DataClass = iota
+	// DataCompressed is a known compressed format (gzip, zip, xz, ...), which is high-entropy without being encrypted.
+	DataCompressed
+	// DataEncrypted is a known encrypted format (LUKS, age, OpenPGP, ...), or an encrypted zip archive.
+	DataEncrypted
+	// DataHighEntropy is random-looking data of no known format, typically encrypted (eg a VeraCrypt container) or random.
+	DataHighEntropy
+)
+
+// String returns the name of the class.
+func (c DataClass) String() string {
+	switch c {
+	case DataPlain:
+		return "plain"
+	case DataCompressed:
+		return "compressed"
+	case DataEncrypted:
+		return "encrypted"
+	case DataHighEntropy:
+		return "high-entropy"
+	}
+	return "unknown"
+}
+
+// Classification is the result of Classify.
+type Classification struct {
+	Path  string
+	Class DataClass
+	// Format names the detected container or format (eg "luks", "age", "gzip"), if any.
+	Format string
+	// Entropy is the Shannon entropy of the sampled contents in bits per byte (0 to 8), estimated with Miller–Madow bias correction,
+	// so that short samples of random data are not rated lower than long ones.
+	Entropy float64
+}
+
+// classifySample is the number of bytes read from the start of each classified file.
+const classifySample = 64 << 10
+
+// HighEntropy is the entropy (in bits per byte) above which samples of unknown format are classified as DataHighEntropy.
+var HighEntropy = 7.9
+
+// minEntropySample is the smallest sample whose entropy is considered; shorter samples cannot reach high entropy.
+const minEntropySample = 1024
+
+// dataMagic is a signature of a known format at given offset of a file.
+type dataMagic struct {
+	offset int
+	magic  string
+	format string
+	class  DataClass
+}
+
+// dataMagics are the signatures recognized by Classify.
+var dataMagics = []dataMagic{
+	{0, "LUKS\xba\xbe", "luks", DataEncrypted},
+	{3, "-FVE-FS-", "bitlocker", DataEncrypted},
+	{0, "age-encryption.org/", "age", DataEncrypted},
+	{0, "-----BEGIN AGE ENCRYPTED FILE-----", "age", DataEncrypted},
+	{0, "-----BEGIN PGP MESSAGE-----", "openpgp", DataEncrypted},
+	{0, "Salted__", "openssl", DataEncrypted},
+	{0, "$ANSIBLE_VAULT", "ansible-vault", DataEncrypted},
+	{0, "\x1f\x8b", "gzip", DataCompressed},
+	{0, "BZh", "bzip2", DataCompressed},
+	{0, "\xfd7zXZ\x00", "xz", DataCompressed},
+	{0, "\x28\xb5\x2f\xfd", "zstd", DataCompressed},
+	{0, "7z\xbc\xaf\x27\x1c", "7z", DataCompressed},
+	{0, "Rar!\x1a\x07", "rar", DataCompressed},
+	{0, "PK\x03\x04", "zip", DataCompressed},
+}
+
+// Classify reads the start of file path and classifies its contents as plain, compressed, encrypted or high-entropy,
+// from known container signatures (LUKS, BitLocker, age, OpenPGP armor, OpenSSL, Ansible Vault, common compressed formats)
+// and the entropy of the sample. Zip archives with encrypted entries are reported as encrypted.
+// Entropy is only considered for samples of at least 1024 bytes.
+func Classify(path string) (Classification, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return Classification{}, err
+	}
+	defer f.Close()
+	buf := make([]byte, classifySample)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Classification{}, err
+	}
+	return classify(path, buf[:n]), nil
+}
+
+// Classify classifies file path like package's Classify does, if w grants CanRead (see Require).
+func (w *Walker) Classify(path string) (Classification, error) {
+	if err := w.Require(CanRead, "classify", path); err != nil {
+		return Classification{}, err
+	}
+	return Classify(path)
+}
+
+// classify classifies sample, the start of file path.
+func classify(path string, sample []byte) Classification {
+	c := Classification{Path: path, Entropy: entropy(sample)}
+	for _, m := range dataMagics {
+		if len(sample) >= m.offset+len(m.magic) && string(sample[m.offset:m.offset+len(m.magic)]) == m.magic {
+			c.Format, c.Class = m.format, m.class
+			break
+		}
+	}
+	switch {
+	case c.Format == "zip" && zipEncrypted(sample):
+		c.Class = DataEncrypted
+	case c.Format == "" && len(sample) >= minEntropySample && c.Entropy >= HighEntropy:
+		c.Class = DataHighEntropy
+	}
+	return c
+}
+
+// zipEncrypted reports whether a local file header in sample, the start of a zip archive, has the encryption flag set.
+func zipEncrypted(sample []byte) bool {
+	for off := 0; ; {
+		i := bytes.Index(sample[off:], []byte("PK\x03\x04"))
+		if i < 0 || off+i+8 > len(sample) {
+			return false
+		}
+		if sample[off+i+6]&1 != 0 {
+			return true
+		}
+		off += i + 4
+	}
+}
+
+// entropy returns the Shannon entropy of data in bits per byte, estimated with Miller–Madow correction:
+// the plug-in estimate from byte frequencies is biased low by about (m-1)/2n nats for a sample of n bytes with m distinct values.
+func entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var h float64
+	n := float64(len(data))
+	m := 0
+	for _, c := range counts {
+		if c > 0 {
+			m++
+			p := float64(c) / n
+			h -= p * math.Log2(p)
+		}
+	}
+	h += float64(m-1) / (2 * n * math.Ln2)
+	return math.Min(h, 8)
+}
+
+// ScanEncryption walks recursively given directory structure and classifies every regular file with Classify,
+// returning the files classified as encrypted or high-entropy (and also compressed ones, if withCompressed), for security inventory scans.
+// Unreadable files stop the scan with their error.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before ScanEncryption call.
+func ScanEncryption(root string, withCompressed bool) ([]Classification, error) {
+	var found []Classification
+	var firstErr error
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		c, err := Classify(path)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		if c.Class == DataEncrypted || c.Class == DataHighEntropy || withCompressed && c.Class == DataCompressed {
+			found = append(found, c)
+		}
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return found, firstErr
+}
+
+// ScanEncryption classifies files under root like package's ScanEncryption does, if w grants CanRead (see Require).
+func (w *Walker) ScanEncryption(root string, withCompressed bool) ([]Classification, error) {
+	if err := w.Require(CanRead, "classify", root); err != nil {
+		return nil, err
+	}
+	return ScanEncryption(root, withCompressed)
+}
@@ -0,0 +1,90 @@
+package walks
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Entry is a file or directory visited by a walk.
+// Entry implements fs.DirEntry.
+// Entries passed to callbacks or returned by iterators may be reused once the callback returns or the iterator advances
+// (as documented by each of them), and the info of entries not read yet is read lazily, when the entry may have changed or vanished.
+// An entry that is stored beyond that must be copied with Clone, unless the walk retains its entries:
+// Walker's actions are passed cloned entries with RetainEntries set (see WithRetainedEntries),
+// Iterator returns new entries with its RetainEntries set, and WalkChan always sends cloned entries.
+type Entry struct {
+	// Path is the path of the entry, starting with the walked root.
+	Path string
+	// Depth is the level of the entry in walked directory structure, 0 for entries directly under the root.
+	Depth int
+
+	d    fs.DirEntry // entry as read from the directory, if known
+	info os.FileInfo // info of the entry, if already known
+}
+
+// infoEntry is an fs.DirEntry of already known info, eg of the target of a followed symbolic link.
+type infoEntry struct {
+	os.FileInfo
+}
+
+// Type returns the type bits of the entry.
+func (e infoEntry) Type() fs.FileMode {
+	return e.Mode().Type()
+}
+
+// Info returns the info of the entry.
+func (e infoEntry) Info() (fs.FileInfo, error) {
+	return e.FileInfo, nil
+}
+
+// Name returns the base name of the entry.
+func (e Entry) Name() string {
+	switch {
+	case e.d != nil:
+		return e.d.Name()
+	case e.info != nil:
+		return e.info.Name()
+	}
+	return filepath.Base(e.Path)
+}
+
+// IsDir reports whether the entry is a directory.
+func (e Entry) IsDir() bool {
+	return e.Type().IsDir()
+}
+
+// Type returns the type bits of the entry.
+func (e Entry) Type() fs.FileMode {
+	switch {
+	case e.d != nil:
+		return e.d.Type()
+	case e.info != nil:
+		return e.info.Mode().Type()
+	}
+	return 0
+}
+
+// Info returns the FileInfo of the entry.
+// When the walk has not already needed it, the info is read (with lstat) on first call.
+func (e Entry) Info() (fs.FileInfo, error) {
+	switch {
+	case e.info != nil:
+		return e.info, nil
+	case e.d != nil:
+		return e.d.Info()
+	}
+	return DefaultFS.Lstat(e.Path)
+}
+
+// Clone returns a copy of the entry, that is independent of the walk it came from and safe to keep and share between goroutines.
+// If the info of the entry was not read yet, it is read now, so that the copy describes the entry as it was walked
+// (if reading fails, the copy reads it on its first Info call instead).
+func (e Entry) Clone() Entry {
+	if e.info == nil && e.d != nil {
+		if info, err := e.d.Info(); err == nil {
+			e.info = info
+		}
+	}
+	return e
+}
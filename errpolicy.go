@@ -0,0 +1,81 @@
+package walks
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrorPolicy controls how Walker treats errors of the walk itself: directories that cannot be listed or opened,
+// unreadable ignore files, broken symbolic links and entries of unsupported types.
+// Errors returned by the actions are not affected, they are handled as documented by WalkErr.
+type ErrorPolicy uint
+
+const (
+	// ErrorsAbort stops the walk on the first error and returns it. This is the default.
+	ErrorsAbort ErrorPolicy = iota
+	// ErrorsSkip skips the entry (or the directory contents) that failed and continues the walk.
+	ErrorsSkip
+	// ErrorsCollect skips like ErrorsSkip and returns all errors as WalkErrors when the walk is done.
+	ErrorsCollect
+)
+
+// WalkErrors are the errors collected by a walk in ErrorsCollect policy, in the order they occurred.
+type WalkErrors []error
+
+func (e WalkErrors) Error() string {
+	var b strings.Builder
+	b.WriteString("walks: " + strconv.Itoa(len(e)) + " error")
+	if len(e) != 1 {
+		b.WriteString("s")
+	}
+	for _, err := range e {
+		b.WriteString("\n\t" + err.Error())
+	}
+	return b.String()
+}
+
+// Unwrap returns the collected errors, so that errors.Is and errors.As look through them.
+func (e WalkErrors) Unwrap() []error {
+	return e
+}
+
+// errorSink collects errors of one walk.
+type errorSink struct {
+	mu     sync.Mutex
+	errs   WalkErrors
+	counts *walkCounts // counts of the walk, to count the errors in, if not nil
+}
+
+// pathError applies Walker's ErrorAction and Errors policy to err of the walk at path, returning the error to stop the walk with (nil to continue).
+func (w *Walker) pathError(sink *errorSink, path string, err error) error {
+	if sink.counts != nil {
+		atomic.AddInt64(&sink.counts.errors, 1)
+	}
+	if w.ErrorAction != nil {
+		if err = w.ErrorAction(path, err); err == nil || err == SkipAll {
+			return err
+		}
+	}
+	switch w.Errors {
+	case ErrorsSkip:
+		return nil
+	case ErrorsCollect:
+		sink.mu.Lock()
+		sink.errs = append(sink.errs, err)
+		sink.mu.Unlock()
+		return nil
+	}
+	return err
+}
+
+// err returns the collected errors, or nil if there are none.
+func (s *errorSink) err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.errs) == 0 {
+		return nil
+	}
+	return append(WalkErrors(nil), s.errs...)
+}
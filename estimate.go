@@ -0,0 +1,140 @@
+package walks
+
+import (
+	"math"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Estimation is an order-of-magnitude estimate of the size of a directory structure, as returned by Estimate.
+type Estimation struct {
+	Files int64 // regular files
+	Dirs  int64 // directories, not counting the root
+	Bytes int64 // total size of regular files
+	// Probes is the number of random root-to-leaf paths sampled; more probes make the estimate more reliable.
+	Probes int
+}
+
+// maxEstimateProbes limits the number of probes of Estimate, whatever its budget.
+const maxEstimateProbes = 100000
+
+// estimateCheck is the number of probes between convergence checks of Estimate, and estimateConverged the relative change
+// of the estimated number of files between two checks, under which the estimate is taken as converged.
+const (
+	estimateCheck     = 256
+	estimateConverged = 0.005
+)
+
+// Estimate approximates the size of the directory structure under root within time budget, without walking all of it,
+// eg to choose concurrency or to warn users before huge operations.
+// It samples random paths from root down to a directory with no subdirectories (Knuth's estimator):
+// contents of each visited directory are weighted by the product of the fan-outs above it,
+// and the estimates of all probes are averaged. Directory listings are cached between probes, so small trees are effectively counted:
+// once every directory has been listed, the exact counts are returned right away.
+// Probing also stops early, when the estimate has converged, or after maxEstimateProbes probes. At least one probe is made, even if budget is already spent.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Estimate call.
+func Estimate(root string, budget time.Duration) (Estimation, error) {
+	listings := make(map[string][]os.FileInfo)
+	pending := make(map[string]bool) // directories seen, but not listed yet
+	list := func(dir string) ([]os.FileInfo, error) {
+		if infos, ok := listings[dir]; ok {
+			return infos, nil
+		}
+		delete(pending, dir)
+		infos, err := readDir(DefaultFS, dir)
+		if err != nil {
+			listings[dir] = nil
+			return nil, err
+		}
+		listings[dir] = infos
+		for _, info := range infos {
+			pathName := joinPath(dir, info.Name())
+			if _, listed := listings[pathName]; info.IsDir() && !listed && !ignoredPath(pathName, true) {
+				pending[pathName] = true
+			}
+		}
+		return infos, nil
+	}
+	if _, err := list(root); err != nil {
+		return Estimation{}, err
+	}
+	rnd := rand.New(rand.NewSource(DefaultClock.Now().UnixNano()))
+	deadline := DefaultClock.Now().Add(budget)
+	var files, dirs, bytes float64
+	var est Estimation
+	checked := 0.0 // estimated files at the last convergence check
+	for est.Probes == 0 || DefaultClock.Now().Before(deadline) {
+		if est.Probes > 0 && len(pending) == 0 {
+			exact := countListings(root, listings)
+			exact.Probes = est.Probes
+			return exact, nil
+		}
+		if est.Probes >= maxEstimateProbes {
+			break
+		}
+		if est.Probes > 0 && est.Probes%estimateCheck == 0 {
+			mean := files / float64(est.Probes)
+			if checked > 0 && math.Abs(mean-checked) <= estimateConverged*checked {
+				break
+			}
+			checked = mean
+		}
+		est.Probes++
+		dir, weight := root, 1.0
+		for {
+			infos, err := list(dir)
+			if err != nil {
+				break
+			}
+			var subdirs []string
+			for _, info := range infos {
+				pathName := joinPath(dir, info.Name())
+				if ignoredPath(pathName, info.IsDir()) {
+					continue
+				}
+				switch {
+				case info.IsDir():
+					subdirs = append(subdirs, pathName)
+				case info.Mode().IsRegular():
+					files += weight
+					bytes += weight * float64(info.Size())
+				}
+			}
+			dirs += weight * float64(len(subdirs))
+			if len(subdirs) == 0 {
+				break
+			}
+			weight *= float64(len(subdirs))
+			dir = subdirs[rnd.Intn(len(subdirs))]
+		}
+	}
+	n := float64(est.Probes)
+	est.Files, est.Dirs, est.Bytes = int64(files/n), int64(dirs/n), int64(bytes/n)
+	return est, nil
+}
+
+// countListings counts the entries under root in complete cached listings of Estimate.
+func countListings(root string, listings map[string][]os.FileInfo) Estimation {
+	var est Estimation
+	stack := []string{root}
+	for len(stack) > 0 {
+		dir := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, info := range listings[dir] {
+			pathName := joinPath(dir, info.Name())
+			if ignoredPath(pathName, info.IsDir()) {
+				continue
+			}
+			switch {
+			case info.IsDir():
+				est.Dirs++
+				stack = append(stack, pathName)
+			case info.Mode().IsRegular():
+				est.Files++
+				est.Bytes += info.Size()
+			}
+		}
+	}
+	return est
+}
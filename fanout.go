@@ -0,0 +1,106 @@
+package walks
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrAllSinksFailed is returned by FanOut's Put, when every destination has failed.
+var ErrAllSinksFailed = errors.New("walks: all sinks failed")
+
+// FanOutError reports the destinations of a FanOut, that failed, by their index.
+type FanOutError map[int]error
+
+// Error lists the failed destinations in order.
+func (e FanOutError) Error() string {
+	var indexes []int
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	var errs []string
+	for _, i := range indexes {
+		errs = append(errs, fmt.Sprintf("sink %v: %v", i, e[i]))
+	}
+	return "walks: " + strings.Join(errs, "; ")
+}
+
+// FanOut is a Sink replicating the walked stream to several sinks at once (eg a manifest, a database and an archive in one pass).
+// Destinations fail independently: a destination returning an error from Put gets no more paths, while the others continue.
+// FanOut is safe for concurrent use, if all of its destinations are.
+type FanOut struct {
+	// OnError, if not nil, is called when destination i fails.
+	OnError func(i int, err error)
+
+	sinks  []Sink
+	mu     sync.Mutex
+	failed FanOutError
+}
+
+// NewFanOut returns a FanOut replicating to sinks.
+func NewFanOut(sinks ...Sink) *FanOut {
+	return &FanOut{sinks: sinks, failed: make(FanOutError)}
+}
+
+// Put passes path to every destination, that has not failed yet.
+// ErrAllSinksFailed is returned once no destination is left, which stops WalkSink.
+func (f *FanOut) Put(path string) error {
+	for i, sink := range f.sinks {
+		if f.err(i) != nil {
+			continue
+		}
+		if err := sink.Put(path); err != nil {
+			f.fail(i, err)
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failed) == len(f.sinks) {
+		return ErrAllSinksFailed
+	}
+	return nil
+}
+
+// Close closes every destination, returning FanOutError if any of them failed (in Put or in Close).
+func (f *FanOut) Close() error {
+	for i, sink := range f.sinks {
+		if err := sink.Close(); err != nil && f.err(i) == nil {
+			f.fail(i, err)
+		}
+	}
+	return f.Err()
+}
+
+// Err returns FanOutError with the destinations failed so far, or nil if there are none.
+func (f *FanOut) Err() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.failed) == 0 {
+		return nil
+	}
+	failed := make(FanOutError, len(f.failed))
+	for i, err := range f.failed {
+		failed[i] = err
+	}
+	return failed
+}
+
+// err returns the error of destination i, if it has failed.
+func (f *FanOut) err(i int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.failed[i]
+}
+
+// fail records the failure of destination i.
+func (f *FanOut) fail(i int, err error) {
+	f.mu.Lock()
+	f.failed[i] = err
+	f.mu.Unlock()
+	if f.OnError != nil {
+		f.OnError(i, err)
+	}
+}
@@ -0,0 +1,40 @@
+package walks
+
+import "syscall"
+
+// fsTypes are the names of filesystem types by their statfs magic numbers.
+var fsTypes = map[int64]string{
+	0x9123683e: "btrfs",
+	0x2fc12fc1: "zfs",
+	0xef53:     "ext4",
+	0x58465342: "xfs",
+	0x01021994: "tmpfs",
+	0x794c7630: "overlay",
+	0x6969:     "nfs",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0xf2f52010: "f2fs",
+	0x4d44:     "vfat",
+	0x5346544e: "ntfs",
+	0x9fa0:     "proc",
+	0x62656572: "sysfs",
+	0x0027e0eb: "cgroup",
+	0x63677270: "cgroup2",
+	0x0187:     "autofs",
+	0x517b:     "smbfs",
+	0x5346414f: "afs",
+	0x00c36400: "ceph",
+	0x01021997: "9p",
+	0x0bd00bd0: "lustre",
+	0x47504653: "gpfs",
+}
+
+// fsType returns the type name of the filesystem of path, or "" if it is not known.
+func fsType(path string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return ""
+	}
+	return fsTypes[int64(uint32(st.Type))]
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+// fsType returns "", filesystem types are only detected on Linux.
+func fsType(path string) string {
+	return ""
+}
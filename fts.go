@@ -0,0 +1,126 @@
+package walks
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// Iterator is a low-level, allocation-lean cursor over a directory structure, modeled on fts(3).
+// Entries are returned one at a time by Next in depth-first order, with each directory's entries sorted by name;
+// Skip and Again give explicit control over the traversal.
+// Entry returned by Next is reused by the following call: keep it with Entry.Clone, or set RetainEntries.
+type Iterator struct {
+	// Post makes Next return every walked directory a second time, after its contents (see PostOrder).
+	Post bool
+	// RetainEntries makes Next return a new Entry on every call, which stays valid after the following calls.
+	RetainEntries bool
+
+	stack []iterDir
+	cur   Entry
+	post  bool // cur is the post-order visit of a directory
+	skip  bool
+	again bool
+	err   error
+}
+
+// iterDir is a directory being iterated, with next being the index of its next entry.
+type iterDir struct {
+	entry   Entry // the directory itself
+	entries []fs.DirEntry
+	next    int
+}
+
+// NewIterator returns an Iterator over the entries under directory root.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before NewIterator call.
+func NewIterator(root string) *Iterator {
+	it := &Iterator{}
+	it.push(Entry{Path: root, Depth: -1})
+	return it
+}
+
+// push starts iterating the contents of directory dir.
+func (it *Iterator) push(dir Entry) {
+	entries, err := DefaultFS.ReadDir(dir.Path)
+	if err != nil {
+		it.err = err
+		return
+	}
+	it.stack = append(it.stack, iterDir{entry: dir, entries: entries})
+}
+
+// Next returns the next entry, or nil when the iteration is done or has failed (see Err).
+// Directories are descended into after they are returned, unless Skip is called before the following Next.
+func (it *Iterator) Next() *Entry {
+	if it.err != nil {
+		return nil
+	}
+	if it.again {
+		it.again = false
+		info, err := DefaultFS.Lstat(it.cur.Path)
+		if err != nil {
+			it.err = err
+			return nil
+		}
+		it.cur.d, it.cur.info = nil, info
+		return it.entry()
+	}
+	if !it.post && it.cur.Path != "" && it.cur.IsDir() && !it.skip {
+		it.push(it.cur)
+		if it.err != nil {
+			return nil
+		}
+	}
+	it.skip = false
+	for len(it.stack) > 0 {
+		top := &it.stack[len(it.stack)-1]
+		if top.next == len(top.entries) {
+			dir := top.entry
+			it.stack = it.stack[:len(it.stack)-1]
+			if it.Post && len(it.stack) > 0 {
+				it.cur, it.post = dir, true
+				return it.entry()
+			}
+			continue
+		}
+		d := top.entries[top.next]
+		top.next++
+		pathName := filepath.Join(top.entry.Path, d.Name())
+		if ignoredPath(pathName, d.IsDir()) {
+			continue
+		}
+		it.cur = Entry{Path: pathName, Depth: top.entry.Depth + 1, d: d}
+		it.post = false
+		return it.entry()
+	}
+	it.cur = Entry{}
+	return nil
+}
+
+// entry returns the current entry, as a copy if RetainEntries is set.
+func (it *Iterator) entry() *Entry {
+	if it.RetainEntries {
+		e := it.cur
+		return &e
+	}
+	return &it.cur
+}
+
+// Skip makes the following Next not descend into the directory returned last.
+func (it *Iterator) Skip() {
+	it.skip = true
+}
+
+// Again makes the following Next return the entry returned last once more, with its info read again.
+func (it *Iterator) Again() {
+	it.again = true
+}
+
+// PostOrder reports whether the entry returned last is a directory visited after its contents (only with Post set).
+func (it *Iterator) PostOrder() bool {
+	return it.post
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,543 @@
+package fuseview
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/moledoc/walks"
+)
+
+// The view is served by speaking the FUSE kernel protocol (see linux/fuse.h) directly over /dev/fuse,
+// implementing only the read-only subset of it, not to depend on libfuse nor third-party modules.
+
+// FUSE protocol version spoken.
+const (
+	fuseMajor = 7
+	fuseMinor = 31
+)
+
+// FUSE opcodes used.
+const (
+	fuseLookup      = 1
+	fuseForget      = 2
+	fuseGetattr     = 3
+	fuseReadlink    = 5
+	fuseOpen        = 14
+	fuseRead        = 15
+	fuseStatfs      = 17
+	fuseRelease     = 18
+	fuseFlush       = 25
+	fuseInit        = 26
+	fuseOpendir     = 27
+	fuseReaddir     = 28
+	fuseReleasedir  = 29
+	fuseInterrupt   = 36
+	fuseDestroy     = 38
+	fuseBatchForget = 42
+)
+
+// fuseMaxWrite is the largest payload of a message; reads are split by the kernel accordingly.
+const fuseMaxWrite = 128 << 10
+
+// fuseTTL is how long the kernel may cache entries and attributes; the view never changes.
+const fuseTTL = 60 * 60
+
+type fuseInHeader struct {
+	Len     uint32
+	Opcode  uint32
+	Unique  uint64
+	NodeID  uint64
+	UID     uint32
+	GID     uint32
+	PID     uint32
+	Extlen  uint16
+	Padding uint16
+}
+
+type fuseOutHeader struct {
+	Len    uint32
+	Error  int32
+	Unique uint64
+}
+
+type fuseInitIn struct {
+	Major        uint32
+	Minor        uint32
+	MaxReadahead uint32
+	Flags        uint32
+}
+
+type fuseInitOut struct {
+	Major               uint32
+	Minor               uint32
+	MaxReadahead        uint32
+	Flags               uint32
+	MaxBackground       uint16
+	CongestionThreshold uint16
+	MaxWrite            uint32
+	TimeGran            uint32
+	MaxPages            uint16
+	MapAlignment        uint16
+	Flags2              uint32
+	Unused              [7]uint32
+}
+
+type fuseAttr struct {
+	Ino       uint64
+	Size      uint64
+	Blocks    uint64
+	Atime     uint64
+	Mtime     uint64
+	Ctime     uint64
+	Atimensec uint32
+	Mtimensec uint32
+	Ctimensec uint32
+	Mode      uint32
+	Nlink     uint32
+	UID       uint32
+	GID       uint32
+	Rdev      uint32
+	Blksize   uint32
+	Flags     uint32
+}
+
+type fuseEntryOut struct {
+	NodeID         uint64
+	Generation     uint64
+	EntryValid     uint64
+	AttrValid      uint64
+	EntryValidNsec uint32
+	AttrValidNsec  uint32
+	Attr           fuseAttr
+}
+
+type fuseAttrOut struct {
+	AttrValid     uint64
+	AttrValidNsec uint32
+	Dummy         uint32
+	Attr          fuseAttr
+}
+
+type fuseOpenOut struct {
+	Fh        uint64
+	OpenFlags uint32
+	Padding   uint32
+}
+
+type fuseReadIn struct {
+	Fh        uint64
+	Offset    uint64
+	Size      uint32
+	ReadFlags uint32
+	LockOwner uint64
+	Flags     uint32
+	Padding   uint32
+}
+
+type fuseKstatfs struct {
+	Blocks  uint64
+	Bfree   uint64
+	Bavail  uint64
+	Files   uint64
+	Ffree   uint64
+	Bsize   uint32
+	Namelen uint32
+	Frsize  uint32
+	Padding uint32
+	Spare   [6]uint32
+}
+
+type fuseDirent struct {
+	Ino     uint64
+	Off     uint64
+	Namelen uint32
+	Type    uint32
+}
+
+// fuseOpenKeepCache lets the kernel keep cached file contents across opens.
+const fuseOpenKeepCache = 1 << 1
+
+// bytesOf returns the n bytes at p.
+func bytesOf(p unsafe.Pointer, n uintptr) []byte {
+	return (*[1 << 30]byte)(p)[:n:n]
+}
+
+// fuseServer serves a View over an open /dev/fuse.
+type fuseServer struct {
+	v   *View
+	dev *os.File
+
+	mu    sync.Mutex
+	files map[uint64]fs.File // open files by handle
+	next  uint64
+}
+
+// mount mounts v at mountpoint and serves it until ctx is done or it is unmounted.
+func mount(ctx context.Context, v *View, mountpoint string) error {
+	if !v.nodes[0].info.IsDir() {
+		return fmt.Errorf("fuseview: view of %v is not a directory", v.Root)
+	}
+	dev, err := fuseMount(mountpoint)
+	if err != nil {
+		return err
+	}
+	s := &fuseServer{v: v, dev: dev, files: make(map[uint64]fs.File)}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			fuseUnmount(mountpoint)
+		case <-done:
+		}
+	}()
+	err = s.serve()
+	s.mu.Lock()
+	for _, f := range s.files {
+		f.Close()
+	}
+	s.mu.Unlock()
+	dev.Close()
+	if err == nil && ctx.Err() == nil {
+		// unmounted externally
+		return nil
+	}
+	return err
+}
+
+// fuseMount mounts a FUSE filesystem at mountpoint, returning the /dev/fuse connection:
+// directly with mount(2) when permitted, otherwise with fusermount3 or fusermount.
+func fuseMount(mountpoint string) (*os.File, error) {
+	fd, err := syscall.Open("/dev/fuse", syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return fusermount(mountpoint)
+	}
+	opts := fmt.Sprintf("fd=%d,rootmode=40000,user_id=%d,group_id=%d,default_permissions", fd, os.Getuid(), os.Getgid())
+	if err := syscall.Mount("walks", mountpoint, "fuse.walks", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_RDONLY, opts); err != nil {
+		syscall.Close(fd)
+		if err == syscall.EPERM {
+			return fusermount(mountpoint)
+		}
+		return nil, &os.PathError{Op: "mount", Path: mountpoint, Err: err}
+	}
+	return os.NewFile(uintptr(fd), "/dev/fuse"), nil
+}
+
+// fusermount mounts a FUSE filesystem at mountpoint with the setuid fusermount helper, which passes the opened /dev/fuse back over a socket.
+func fusermount(mountpoint string) (*os.File, error) {
+	helper, err := exec.LookPath("fusermount3")
+	if err != nil {
+		if helper, err = exec.LookPath("fusermount"); err != nil {
+			return nil, fmt.Errorf("fuseview: mounting %v needs root rights or fusermount: %v", mountpoint, err)
+		}
+	}
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	local, remote := os.NewFile(uintptr(fds[0]), "fusermount"), os.NewFile(uintptr(fds[1]), "fusermount")
+	defer local.Close()
+	defer remote.Close()
+	cmd := exec.Command(helper, "-o", "ro,nosuid,nodev,default_permissions,fsname=walks,subtype=walks", "--", mountpoint)
+	cmd.Env = append(os.Environ(), "_FUSE_COMMFD=3")
+	cmd.ExtraFiles = []*os.File{remote}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	remote.Close()
+	buf, oob := make([]byte, 1), make([]byte, syscall.CmsgSpace(4))
+	_, oobn, _, _, rerr := syscall.Recvmsg(int(local.Fd()), buf, oob, 0)
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("fuseview: %v: %v", helper, err)
+	}
+	if rerr != nil {
+		return nil, rerr
+	}
+	msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+	if err != nil || len(msgs) == 0 {
+		return nil, fmt.Errorf("fuseview: %v passed no file descriptor", helper)
+	}
+	passed, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(passed) == 0 {
+		return nil, fmt.Errorf("fuseview: %v passed no file descriptor", helper)
+	}
+	syscall.CloseOnExec(passed[0])
+	return os.NewFile(uintptr(passed[0]), "/dev/fuse"), nil
+}
+
+// fuseUnmount unmounts the FUSE filesystem at mountpoint, directly or with fusermount.
+func fuseUnmount(mountpoint string) error {
+	if err := syscall.Unmount(mountpoint, 0); err == nil {
+		return nil
+	}
+	for _, helper := range []string{"fusermount3", "fusermount"} {
+		if path, err := exec.LookPath(helper); err == nil {
+			return exec.Command(path, "-u", "-z", mountpoint).Run()
+		}
+	}
+	return syscall.Unmount(mountpoint, syscall.MNT_DETACH)
+}
+
+// serve answers requests of the kernel until the filesystem is unmounted.
+func (s *fuseServer) serve() error {
+	buf := make([]byte, fuseMaxWrite+4096)
+	for {
+		n, err := syscall.Read(int(s.dev.Fd()), buf)
+		switch err {
+		case nil:
+		case syscall.EINTR, syscall.EAGAIN, syscall.ENOENT:
+			// interrupted, or request aborted by the kernel
+			continue
+		case syscall.ENODEV:
+			return nil
+		default:
+			return &os.PathError{Op: "read", Path: "/dev/fuse", Err: err}
+		}
+		if n < int(unsafe.Sizeof(fuseInHeader{})) {
+			continue
+		}
+		hdr := *(*fuseInHeader)(unsafe.Pointer(&buf[0]))
+		body := buf[unsafe.Sizeof(fuseInHeader{}):n]
+		if hdr.Opcode == fuseDestroy {
+			s.reply(hdr, nil, 0)
+			return nil
+		}
+		s.handle(hdr, body)
+	}
+}
+
+// handle answers request hdr with body.
+func (s *fuseServer) handle(hdr fuseInHeader, body []byte) {
+	var node *viewNode
+	if hdr.NodeID >= 1 && hdr.NodeID <= uint64(len(s.v.nodes)) {
+		node = s.v.nodes[hdr.NodeID-1]
+	}
+	switch hdr.Opcode {
+	case fuseForget, fuseBatchForget, fuseInterrupt:
+		// no reply; nodes live as long as the view
+		return
+	case fuseInit:
+		in := (*fuseInitIn)(unsafe.Pointer(&body[0]))
+		if in.Major < fuseMajor {
+			s.reply(hdr, nil, syscall.EPROTO)
+			return
+		}
+		out := fuseInitOut{Major: fuseMajor, Minor: fuseMinor, MaxReadahead: in.MaxReadahead, MaxWrite: fuseMaxWrite, TimeGran: 1, MaxBackground: 16, CongestionThreshold: 12}
+		if in.Major == fuseMajor && in.Minor < fuseMinor {
+			out.Minor = in.Minor
+		}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+		return
+	case fuseStatfs:
+		out := fuseKstatfs{Files: uint64(len(s.v.nodes)), Bsize: 4096, Frsize: 4096, Namelen: 255}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+		return
+	}
+	if node == nil {
+		s.reply(hdr, nil, syscall.ENOENT)
+		return
+	}
+	switch hdr.Opcode {
+	case fuseLookup:
+		name := string(body)
+		if i := indexNUL(body); i >= 0 {
+			name = string(body[:i])
+		}
+		child := node.lookup(name)
+		if child == nil {
+			s.reply(hdr, nil, syscall.ENOENT)
+			return
+		}
+		out := fuseEntryOut{NodeID: child.id, EntryValid: fuseTTL, AttrValid: fuseTTL, Attr: child.attr()}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+	case fuseGetattr:
+		out := fuseAttrOut{AttrValid: fuseTTL, Attr: node.attr()}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+	case fuseReadlink:
+		if node.info.Mode()&os.ModeSymlink == 0 {
+			s.reply(hdr, nil, syscall.EINVAL)
+			return
+		}
+		target, err := s.v.readlink(node)
+		if err != nil {
+			s.reply(hdr, nil, errno(err))
+			return
+		}
+		s.reply(hdr, []byte(target), 0)
+	case fuseOpendir:
+		if !node.info.IsDir() {
+			s.reply(hdr, nil, syscall.ENOTDIR)
+			return
+		}
+		out := fuseOpenOut{OpenFlags: fuseOpenKeepCache}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+	case fuseReaddir:
+		in := (*fuseReadIn)(unsafe.Pointer(&body[0]))
+		s.reply(hdr, node.dirents(in.Offset, int(in.Size)), 0)
+	case fuseReleasedir, fuseFlush:
+		s.reply(hdr, nil, 0)
+	case fuseOpen:
+		if !node.info.Mode().IsRegular() {
+			s.reply(hdr, nil, syscall.EINVAL)
+			return
+		}
+		if flags := *(*uint32)(unsafe.Pointer(&body[0])); flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+			s.reply(hdr, nil, syscall.EROFS)
+			return
+		}
+		f, err := s.v.fsys.Open(node.path)
+		if err != nil {
+			s.reply(hdr, nil, errno(err))
+			return
+		}
+		s.mu.Lock()
+		s.next++
+		fh := s.next
+		s.files[fh] = f
+		s.mu.Unlock()
+		out := fuseOpenOut{Fh: fh, OpenFlags: fuseOpenKeepCache}
+		s.reply(hdr, bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out)), 0)
+	case fuseRead:
+		in := (*fuseReadIn)(unsafe.Pointer(&body[0]))
+		s.mu.Lock()
+		f := s.files[in.Fh]
+		s.mu.Unlock()
+		if f == nil {
+			s.reply(hdr, nil, syscall.EBADF)
+			return
+		}
+		data := make([]byte, in.Size)
+		n, err := readAt(f, data, int64(in.Offset))
+		if err != nil && err != io.EOF {
+			s.reply(hdr, nil, errno(err))
+			return
+		}
+		s.reply(hdr, data[:n], 0)
+	case fuseRelease:
+		in := (*fuseReadIn)(unsafe.Pointer(&body[0]))
+		s.mu.Lock()
+		if f := s.files[in.Fh]; f != nil {
+			f.Close()
+			delete(s.files, in.Fh)
+		}
+		s.mu.Unlock()
+		s.reply(hdr, nil, 0)
+	default:
+		s.reply(hdr, nil, syscall.ENOSYS)
+	}
+}
+
+// reply answers request hdr with payload, or with error errno if not 0.
+func (s *fuseServer) reply(hdr fuseInHeader, payload []byte, errno syscall.Errno) {
+	out := fuseOutHeader{Len: uint32(unsafe.Sizeof(fuseOutHeader{}) + uintptr(len(payload))), Error: -int32(errno), Unique: hdr.Unique}
+	msg := append(append([]byte(nil), bytesOf(unsafe.Pointer(&out), unsafe.Sizeof(out))...), payload...)
+	// the kernel drops replies to requests interrupted meanwhile, ENOENT is expected then
+	syscall.Write(int(s.dev.Fd()), msg)
+}
+
+// attr returns FUSE attributes of node n.
+func (n *viewNode) attr() fuseAttr {
+	info := n.info
+	a := fuseAttr{
+		Ino:     n.id,
+		Size:    uint64(info.Size()),
+		Blocks:  (uint64(info.Size()) + 511) / 512,
+		Mtime:   uint64(info.ModTime().Unix()),
+		Nlink:   1,
+		Blksize: 4096,
+	}
+	a.Mtimensec = uint32(info.ModTime().Nanosecond())
+	a.Atime, a.Atimensec, a.Ctime, a.Ctimensec = a.Mtime, a.Mtimensec, a.Mtime, a.Mtimensec
+	if sx, ok := info.Sys().(*walks.Statx); ok {
+		a.Mode, a.UID, a.GID = uint32(sx.Mode), sx.UID, sx.GID
+		if !sx.Atime.IsZero() {
+			a.Atime, a.Atimensec = uint64(sx.Atime.Unix()), uint32(sx.Atime.Nanosecond())
+			a.Ctime, a.Ctimensec = uint64(sx.Ctime.Unix()), uint32(sx.Ctime.Nanosecond())
+		}
+	} else if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		a.Mode, a.UID, a.GID = st.Mode, st.Uid, st.Gid
+		a.Atime, a.Atimensec = uint64(st.Atim.Sec), uint32(st.Atim.Nsec)
+		a.Ctime, a.Ctimensec = uint64(st.Ctim.Sec), uint32(st.Ctim.Nsec)
+	} else {
+		a.Mode = uint32(info.Mode().Perm())
+		switch {
+		case info.IsDir():
+			a.Mode |= syscall.S_IFDIR
+		case info.Mode()&os.ModeSymlink != 0:
+			a.Mode |= syscall.S_IFLNK
+		default:
+			a.Mode |= syscall.S_IFREG
+		}
+	}
+	if info.IsDir() {
+		a.Nlink = 2
+		a.Size = 4096
+	}
+	return a
+}
+
+// dirents returns FUSE directory entries of directory node n starting at offset (the index of the entry), that fit in size bytes.
+func (n *viewNode) dirents(offset uint64, size int) []byte {
+	var out []byte
+	for i := offset; i < uint64(len(n.children)); i++ {
+		child := n.children[i]
+		name := child.info.Name()
+		entLen := int(unsafe.Sizeof(fuseDirent{})) + len(name)
+		padded := (entLen + 7) &^ 7
+		if len(out)+padded > size {
+			break
+		}
+		d := fuseDirent{Ino: child.id, Off: i + 1, Namelen: uint32(len(name)), Type: child.attr().Mode >> 12 & 0xf}
+		out = append(out, bytesOf(unsafe.Pointer(&d), unsafe.Sizeof(d))...)
+		out = append(out, name...)
+		out = append(out, make([]byte, padded-entLen)...)
+	}
+	return out
+}
+
+// indexNUL returns the index of the first NUL byte of b, or -1.
+func indexNUL(b []byte) int {
+	for i, c := range b {
+		if c == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// readAt reads len(p) bytes of file f at offset, like io.ReaderAt; files of FSs not reading at offsets are seeked, if they can be.
+func readAt(f fs.File, p []byte, offset int64) (int, error) {
+	switch f := f.(type) {
+	case io.ReaderAt:
+		return f.ReadAt(p, offset)
+	case io.ReadSeeker:
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		n, err := io.ReadFull(f, p)
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return 0, syscall.ESPIPE
+}
+
+// errno returns the errno of err for a FUSE reply, EIO if it has none.
+func errno(err error) syscall.Errno {
+	if pe, ok := err.(*os.PathError); ok {
+		err = pe.Err
+	}
+	if e, ok := err.(syscall.Errno); ok {
+		return e
+	}
+	return syscall.EIO
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package fuseview
+
+import "context"
+
+// mount returns ErrUnsupported, the view can only be mounted on Linux.
+func mount(ctx context.Context, v *View, mountpoint string) error {
+	return ErrUnsupported
+}
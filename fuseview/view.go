@@ -0,0 +1,163 @@
+/*
+Package fuseview makes filtered virtual trees of walks, that can be mounted as read-only FUSE filesystems (on Linux),
+so that external tools can operate on eg "only *.jpg newer than 2023" as if it was an ordinary directory.
+
+It is a package of its own, so that programs not mounting views do not link the FUSE implementation.
+*/
+package fuseview
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/moledoc/walks"
+)
+
+// ErrUnsupported is returned by View's Mount on platforms without FUSE support (anything but Linux).
+var ErrUnsupported = errors.New("fuseview: FUSE is not supported on this platform")
+
+// View is a filtered virtual tree of a walk: the directories and files a Walker passed to its actions, together with the directories leading to them.
+// The tree is fixed when the view is made; file contents are read from the walked files (in the Walker's FS) when accessed.
+type View struct {
+	// Root is the walked root, the top of the view.
+	Root string
+
+	fsys  walks.FS
+	mu    sync.Mutex
+	nodes []*viewNode // by node id - 1
+}
+
+// viewNode is a directory or file in a View.
+type viewNode struct {
+	id       uint64
+	path     string // path of the walked entry
+	info     os.FileInfo
+	children []*viewNode // sorted by name
+}
+
+// New walks recursively given directory structure with w's configuration (including Search and Filter)
+// and returns the view of the entries passed to the actions.
+// Directories and files can be ignored by setting Walker's Ignore, or package walks' Ignore value with SetIgnore function or manually before New call.
+func New(ctx context.Context, w *walks.Walker, root string) (*View, error) {
+	fsys := w.FS
+	if fsys == nil {
+		fsys = walks.DefaultFS
+	}
+	root = filepath.Clean(root)
+	info, err := fsys.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	v := &View{Root: root, fsys: fsys}
+	// nodes are found by cleaned paths, as the walk's paths under root "." (eg "./a") are not clean; they keep the walk's form
+	byPath := map[string]*viewNode{root: v.node(root, info)}
+	var add func(path string, info os.FileInfo) (*viewNode, error)
+	add = func(path string, info os.FileInfo) (*viewNode, error) {
+		key := filepath.Clean(path)
+		if n, ok := byPath[key]; ok {
+			if info != nil {
+				n.info = info
+			}
+			return n, nil
+		}
+		if info == nil {
+			var err error
+			if info, err = fsys.Lstat(path); err != nil {
+				return nil, err
+			}
+		}
+		parent, err := add(parentPath(path), nil)
+		if err != nil {
+			return nil, err
+		}
+		n := v.node(path, info)
+		byPath[key] = n
+		parent.children = append(parent.children, n)
+		return n, nil
+	}
+	action := func(e walks.Entry) error {
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		_, err = add(e.Path, info)
+		return err
+	}
+	if err := w.WalkEntries(ctx, root, action, action); err != nil {
+		return nil, err
+	}
+	for _, n := range v.nodes {
+		sort.Slice(n.children, func(i, j int) bool { return n.children[i].info.Name() < n.children[j].info.Name() })
+	}
+	return v, nil
+}
+
+// parentPath returns path without its last element, in the form of the walk's paths (eg "./a" of "./a/b", where filepath.Dir gives "a").
+func parentPath(path string) string {
+	i := len(path) - 1
+	for i > 0 && !os.IsPathSeparator(path[i]) {
+		i--
+	}
+	if i <= 0 || i == len(path)-1 {
+		return filepath.Dir(path)
+	}
+	return path[:i]
+}
+
+// node adds a node for path described by info to the view.
+func (v *View) node(path string, info os.FileInfo) *viewNode {
+	n := &viewNode{id: uint64(len(v.nodes) + 1), path: path, info: info}
+	v.nodes = append(v.nodes, n)
+	return n
+}
+
+// Paths returns the paths of all entries in the view (excluding the root), sorted.
+func (v *View) Paths() []string {
+	paths := make([]string, 0, len(v.nodes))
+	for _, n := range v.nodes[1:] {
+		paths = append(paths, n.path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// lookup returns child name of node n, or nil if there is none.
+func (n *viewNode) lookup(name string) *viewNode {
+	i := sort.Search(len(n.children), func(i int) bool { return n.children[i].info.Name() >= name })
+	if i < len(n.children) && n.children[i].info.Name() == name {
+		return n.children[i]
+	}
+	return nil
+}
+
+// readlink returns the target of symbolic link node n, read with the Readlink method of the view's FS (see walks.FS).
+func (v *View) readlink(n *viewNode) (string, error) {
+	if l, ok := v.fsys.(interface {
+		Readlink(name string) (string, error)
+	}); ok {
+		return l.Readlink(n.path)
+	}
+	return "", &fs.PathError{Op: "readlink", Path: n.path, Err: fs.ErrInvalid}
+}
+
+// Mount mounts the view read-only at directory mountpoint with FUSE (Linux only, experimental) and serves it until ctx is done,
+// when it is unmounted, or until it is unmounted externally (eg with `fusermount -u`), returning nil then.
+// Mounting needs either root rights or fusermount3/fusermount (from the fuse3/fuse package) installed.
+func (v *View) Mount(ctx context.Context, mountpoint string) error {
+	mountpoint, err := filepath.Abs(mountpoint)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(mountpoint, ",") {
+		return errors.New("fuseview: mountpoint must not contain commas")
+	}
+	return mount(ctx, v, mountpoint)
+}
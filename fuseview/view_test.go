@@ -0,0 +1,48 @@
+package fuseview
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/moledoc/walks"
+)
+
+func TestNew(t *testing.T) {
+	files := walks.IOFS{FS: fstest.MapFS{
+		"a.txt":       {Data: []byte("a")},
+		"b.log":       {Data: []byte("b")},
+		"d/c.txt":     {Data: []byte("c")},
+		"d/e/f.txt":   {Data: []byte("f")},
+		"d/e/g.log":   {Data: []byte("g")},
+		"h/i/j/k.log": {Data: []byte("k")},
+	}}
+	// directories leading to the entries in the view are added, once, also under root "." with paths like "./d"
+	for _, root := range []string{".", "d"} {
+		w := walks.New(walks.WithFS(files), walks.WithSearch(regexp.MustCompile(`\.txt$`)))
+		v, err := New(context.Background(), w, root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, path := range v.Paths() {
+			got = append(got, filepath.ToSlash(filepath.Clean(path)))
+		}
+		want := []string{"a.txt", "d", "d/c.txt", "d/e", "d/e/f.txt"}
+		if root == "d" {
+			want = []string{"d/c.txt", "d/e", "d/e/f.txt"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", root, got, want)
+		}
+		if len(v.nodes) != len(want)+1 {
+			t.Errorf("%v: got %v nodes, want %v", root, len(v.nodes), len(want)+1)
+		}
+		if n := v.nodes[0].lookup("e"); root == "d" && (n == nil || n.lookup("f.txt") == nil) {
+			t.Errorf("%v: e/f.txt not found from the root", root)
+		}
+	}
+}
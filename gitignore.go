@@ -0,0 +1,209 @@
+package walks
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// IgnoreFormat is the format of ignore files read by SetIgnore.
+type IgnoreFormat int
+
+const (
+	// IgnoreRegexp reads each line of ignore file as a regexp fragment (with dots matched literally), the default.
+	IgnoreRegexp IgnoreFormat = iota
+	// IgnoreGitignore reads ignore file with gitignore semantics, see Gitignore.
+	IgnoreGitignore
+)
+
+// ignoreFormat is the format of ignore files read by SetIgnore, set with SetIgnoreFormat.
+var ignoreFormat = IgnoreRegexp
+
+// SetIgnoreFormat sets the format of ignore files read by following SetIgnore calls.
+func SetIgnoreFormat(format IgnoreFormat) {
+	ignoreFormat = format
+}
+
+// IgnoreRules holds gitignore rules of directories and files to ignore, in addition to Ignore; nil means none.
+// SetIgnore sets it in IgnoreGitignore format.
+var IgnoreRules *Gitignore
+
+// Gitignore is a list of ignore rules with gitignore semantics:
+// blank lines and lines starting with # are skipped; * and ? match within a path element, [...] a character class,
+// and ** a whole number of path elements; trailing / matches directories only; leading ! re-includes what earlier rules excluded
+// (contents of an excluded directory are not walked, so they cannot be re-included); a pattern with a / (other than trailing)
+// is relative to Base, other patterns match the name at any depth.
+type Gitignore struct {
+	// Base is the directory anchored patterns are relative to, the directory of the ignore file.
+	Base  string
+	rules []gitRule
+}
+
+// gitRule is one compiled rule of a Gitignore.
+type gitRule struct {
+	re       *regexp.Regexp
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// ParseGitignore returns the rules in contents (of an ignore file in directory base).
+func ParseGitignore(contents string, base string) (*Gitignore, error) {
+	g := &Gitignore{Base: base}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " ")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule gitRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		rule.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globRegexp(line)
+		if rule.anchored {
+			expr = "^" + expr + "$"
+		} else {
+			expr = "(^|/)" + expr + "$"
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		rule.re = re
+		g.rules = append(g.rules, rule)
+	}
+	return g, nil
+}
+
+// globRegexp translates gitignore glob pattern to a regexp expression (without anchors).
+func globRegexp(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**") && i+2 == len(pattern):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.Replace(class, `\`, `\\`, -1) + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// Match reports whether path (a directory if isDir) is ignored by the rules: the last matching rule decides.
+// Match of nil Gitignore reports false.
+func (g *Gitignore) Match(path string, isDir bool) bool {
+	ignored, _ := g.match(path, isDir)
+	return ignored
+}
+
+// match reports whether path (a directory if isDir) is ignored by the rules, and whether any rule matched it at all.
+func (g *Gitignore) match(path string, isDir bool) (ignored bool, matched bool) {
+	if g == nil {
+		return false, false
+	}
+	rel, under := filepath.Clean(path), false
+	if r, err := filepath.Rel(filepath.Clean(g.Base), rel); err == nil && r != ".." && !strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		rel, under = r, true
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(g.rules) - 1; i >= 0; i-- {
+		rule := g.rules[i]
+		if rule.dirOnly && !isDir || rule.anchored && !under {
+			continue
+		}
+		if rule.re.MatchString(rel) {
+			return !rule.negate, true
+		}
+	}
+	return false, false
+}
+
+// ignoreLayer is the rules of one ignore file found during a walk, layered on the rules of the files found above it.
+type ignoreLayer struct {
+	rules  *Gitignore
+	parent *ignoreLayer
+}
+
+// match reports whether path (a directory if isDir) is ignored by the layers:
+// rules of the deepest ignore file matching path decide, like in git.
+func (l *ignoreLayer) match(path string, isDir bool) bool {
+	for ; l != nil; l = l.parent {
+		if ignored, matched := l.rules.match(path, isDir); matched {
+			return ignored
+		}
+	}
+	return false
+}
+
+// loadIgnoreFiles returns layer with the rules of Walker's IgnoreFiles found among the entries of directory dir layered on it.
+func (w *Walker) loadIgnoreFiles(dir string, entries []fs.DirEntry, layer *ignoreLayer) (*ignoreLayer, error) {
+	for _, name := range w.IgnoreFiles {
+		i := sort.Search(len(entries), func(i int) bool { return entries[i].Name() >= name })
+		if i == len(entries) || entries[i].Name() != name || !entries[i].Type().IsRegular() {
+			continue
+		}
+		f, err := w.fsys().Open(joinPath(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		rules, err := ParseGitignore(string(contents), dir)
+		if err != nil {
+			return nil, err
+		}
+		layer = &ignoreLayer{rules: rules, parent: layer}
+	}
+	return layer, nil
+}
+
+// ignoredPath reports whether path (a directory if isDir) is ignored by package's Ignore or IgnoreRules.
+func ignoredPath(path string, isDir bool) bool {
+	return matchPath(Ignore, path) && Ignore.String() != "" || IgnoreRules.Match(path, isDir)
+}
@@ -0,0 +1,143 @@
+package walks
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Glob returns the paths matching shell pattern like filepath.Glob, but additionally supports `**` as a path element,
+// which matches zero or more directories (eg "src/**/testdata").
+// Matches are sorted and contain no duplicates.
+func Glob(pattern string) ([]string, error) {
+	return glob(DefaultFS, pattern)
+}
+
+// glob is Glob on fsys.
+func glob(fsys FS, pattern string) ([]string, error) {
+	if _, ok := fsys.(OSFS); ok && !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+	elems := strings.Split(filepath.ToSlash(pattern), "/")
+	matches := []string{"."}
+	if elems[0] == "" {
+		matches = []string{string(filepath.Separator)}
+		elems = elems[1:]
+	}
+	for _, elem := range elems {
+		var next []string
+		for _, m := range matches {
+			switch {
+			case elem == "" || elem == ".":
+				next = append(next, m)
+			case elem == "**":
+				next = append(next, m)
+				next = append(next, subdirs(fsys, m)...)
+			case strings.ContainsAny(elem, `*?[\`):
+				entries, err := fsys.ReadDir(m)
+				if err != nil {
+					continue
+				}
+				for _, entry := range entries {
+					matched, err := filepath.Match(elem, entry.Name())
+					if err != nil {
+						return nil, err
+					}
+					if matched {
+						next = append(next, filepath.Join(m, entry.Name()))
+					}
+				}
+			default:
+				path := filepath.Join(m, elem)
+				if _, err := fsys.Lstat(path); err == nil {
+					next = append(next, path)
+				}
+			}
+		}
+		matches = dedup(next)
+	}
+	return matches, nil
+}
+
+// subdirs returns all directories under dir in fsys recursively, not including dir itself.
+func subdirs(fsys FS, dir string) []string {
+	var dirs []string
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			path := filepath.Join(dir, entry.Name())
+			dirs = append(dirs, path)
+			dirs = append(dirs, subdirs(fsys, path)...)
+		}
+	}
+	return dirs
+}
+
+// dedup sorts paths and removes duplicates.
+func dedup(paths []string) []string {
+	sort.Strings(paths)
+	out := paths[:0]
+	for i, path := range paths {
+		if i == 0 || path != paths[i-1] {
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// WalkGlob expands shell pattern (see Glob) into roots in Walker's FS and walks each of them with Walker's configuration.
+// Matched files are passed to fileAction like files found in walked directories (unless ignored, or not matching Search or Filter),
+// and roots nested under another matched directory are not walked twice. Stats of the walk cover all the roots.
+// First error stops the walk and is returned.
+func (w *Walker) WalkGlob(pattern string, fileAction func(string), dirAction func(string)) error {
+	roots, err := glob(w.fsys(), pattern)
+	if err != nil {
+		return err
+	}
+	defer w.cleanTemp()
+	counts := newWalkCounts(w.clock())
+	defer w.record(counts)
+	var walked []string
+	for _, root := range roots {
+		if nested(root, walked) {
+			continue
+		}
+		info, err := w.fsys().Stat(root)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if err := w.walkFile(root, info, w.wrap(plainAction(fileAction)), counts); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.runCounted(context.Background(), root, plainAction(fileAction), plainAction(dirAction), w.Ordered, counts); err != nil {
+			return err
+		}
+		walked = append(walked, root)
+	}
+	return nil
+}
+
+// nested reports whether path is under one of dirs.
+func nested(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) || dir == "." {
+			return true
+		}
+	}
+	return false
+}
+
+// WalkGlob walks concurrently every directory matching shell pattern (see Glob), performing given actions on files and directories.
+// Depth controls the depth of each walked directory structure, like in Walk.
+func WalkGlob(pattern string, fileAction func(string), dirAction func(string), depth int) error {
+	w := New()
+	w.Depth = depth
+	return w.WalkGlob(pattern, fileAction, dirAction)
+}
@@ -0,0 +1,50 @@
+package walks
+
+import (
+	"context"
+	"sync"
+)
+
+// Group is a collection of goroutines working on the same task, such as a walk started with Walker.Go.
+// It mirrors golang.org/x/sync/errgroup.Group: the first function returning an error cancels the Group's context
+// and that error is returned by Wait.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// NewGroup returns a new Group, whose context is derived from ctx.
+func NewGroup(ctx context.Context) *Group {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{ctx: ctx, cancel: cancel}
+}
+
+// Context returns the Group's context, that is cancelled when a function started with Go returns an error or Wait returns.
+func (g *Group) Context() context.Context {
+	return g.ctx
+}
+
+// Go calls fn in a new goroutine.
+// The first call to return a non-nil error cancels the Group's context.
+func (g *Group) Go(fn func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := fn(); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+		}
+	}()
+}
+
+// Wait blocks until all functions started with Go have returned, then returns the first error (if any) returned by them.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	g.cancel()
+	return g.err
+}
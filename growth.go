@@ -0,0 +1,98 @@
+package walks
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// GrowthRule declares when the growth of directories between two snapshots is alarming, eg to catch runaway logs and caches.
+// A directory triggers the rule when it grows by more than Percent or by more than Bytes (whichever is set).
+type GrowthRule struct {
+	// Under is the directory the rule applies to.
+	Under string
+	// Depth is the number of levels of directories below Under also checked by the rule, -1 means all of them.
+	Depth int
+	// Percent is the relative growth limit, eg 50 for 50%; 0 means no relative limit.
+	// Directories, that were empty or did not exist before, are only checked against Bytes.
+	Percent float64
+	// Bytes is the absolute growth limit; 0 means no absolute limit.
+	Bytes int64
+}
+
+// GrowthAlert reports a directory that triggered a GrowthRule.
+type GrowthAlert struct {
+	Path   string
+	Before int64 // total size of regular files under the directory in the older snapshot
+	After  int64 // total size of regular files under the directory in the newer snapshot
+	Rule   GrowthRule
+}
+
+// String returns the alert as text, eg "/var/log grew by 2147483648 bytes (400.0%)".
+func (a GrowthAlert) String() string {
+	growth := a.After - a.Before
+	if a.Before == 0 {
+		return fmt.Sprintf("%v grew by %v bytes", a.Path, growth)
+	}
+	return fmt.Sprintf("%v grew by %v bytes (%.1f%%)", a.Path, growth, float64(growth)*100/float64(a.Before))
+}
+
+// Growth compares the directory sizes of old and newer snapshots against rules, returning alerts sorted by path.
+// A directory triggering several rules is reported once for each of them.
+func Growth(old, newer *Snapshot, rules []GrowthRule) []GrowthAlert {
+	before, after := dirUsage(old), dirUsage(newer)
+	var alerts []GrowthAlert
+	for _, rule := range rules {
+		under := filepath.Clean(rule.Under)
+		for path, entry := range newer.Entries {
+			if !entry.IsDir() {
+				continue
+			}
+			depth, ok := depthUnder(under, path)
+			if !ok || rule.Depth != -1 && depth > rule.Depth {
+				continue
+			}
+			growth := after[path] - before[path]
+			if growth <= 0 {
+				continue
+			}
+			if rule.Bytes > 0 && growth > rule.Bytes ||
+				rule.Percent > 0 && before[path] > 0 && float64(growth)*100/float64(before[path]) > rule.Percent {
+				alerts = append(alerts, GrowthAlert{Path: path, Before: before[path], After: after[path], Rule: rule})
+			}
+		}
+	}
+	sort.SliceStable(alerts, func(i, j int) bool { return alerts[i].Path < alerts[j].Path })
+	return alerts
+}
+
+// depthUnder returns the number of levels path is below dir, and whether it is at or below dir at all.
+func depthUnder(dir string, path string) (int, bool) {
+	path = filepath.Clean(path)
+	if path == dir {
+		return 0, true
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return 0, false
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1, true
+}
+
+// dirUsage returns the total size of regular files under each directory of snap, computed in a single pass.
+func dirUsage(snap *Snapshot) map[string]int64 {
+	usage := make(map[string]int64)
+	for path, entry := range snap.Entries {
+		if !entry.Mode.IsRegular() {
+			continue
+		}
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			usage[dir] += entry.Size
+			if dir == snap.Root || dir == filepath.Dir(dir) {
+				break
+			}
+		}
+	}
+	return usage
+}
@@ -0,0 +1,49 @@
+package walks
+
+import (
+	"errors"
+	"os"
+)
+
+// ErrCycle is reported to Walker's Guard for a directory, that is its own ancestor (eg through a bind mount or a followed symlink).
+var ErrCycle = errors.New("walks: directory cycle")
+
+// ErrDepthCeiling is reported to Walker's Guard for a directory, that is deeper than Walker's DepthCeiling.
+var ErrDepthCeiling = errors.New("walks: depth ceiling reached")
+
+// setID records the device and inode of directory n described by info, for cycle detection.
+func (n *dirNode) setID(info os.FileInfo) {
+	n.dev, n.ino, n.hasID = fileID(info)
+}
+
+// cycles reports whether n is the same directory as one of its ancestors.
+// Only the directories being walked above n are kept, so the set of visited directories is bounded by the depth of the walk.
+func (n *dirNode) cycles() bool {
+	if !n.hasID {
+		return false
+	}
+	for a := n.parent; a != nil; a = a.parent {
+		if a.hasID && a.dev == n.dev && a.ino == n.ino {
+			return true
+		}
+	}
+	return false
+}
+
+// guard checks, whether directory node n at given level may be descended into, reporting the reason to Guard if not.
+func (w *Walker) guard(n *dirNode, level int) bool {
+	var err error
+	switch {
+	case w.DepthCeiling > 0 && level > w.DepthCeiling:
+		err = ErrDepthCeiling
+	case n.cycles():
+		err = ErrCycle
+	default:
+		return true
+	}
+	w.bus.publish(WalkEvent{Kind: EventGuard, Path: n.stats.Path, Err: err})
+	if w.Guard != nil {
+		w.Guard(n.stats.Path, err)
+	}
+	return false
+}
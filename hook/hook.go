@@ -0,0 +1,162 @@
+/*
+Package hook runs walk actions outside of the program: in Go plugins and in external executables speaking JSON over stdio.
+
+It is a package of its own, so that programs not using hooks do not link package plugin, which makes binaries dynamically linked.
+*/
+package hook
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"plugin"
+	"sync"
+
+	"github.com/moledoc/walks"
+)
+
+// PluginSymbol is the name of the symbol looked up by LoadPlugin.
+// Plugin must export it as a function with signature func(path string), or a variable of that type.
+const PluginSymbol = "WalkAction"
+
+// LoadPlugin opens Go plugin (built with `go build -buildmode=plugin`) at path and returns the action it exports as PluginSymbol.
+// The plugin is not opened (which runs its init functions), unless Walker w grants CanExec (see walks' Require); w may be nil.
+// Go plugins are only supported on some platforms (see package plugin), elsewhere an error is returned.
+func LoadPlugin(w *walks.Walker, path string) (func(string), error) {
+	if err := w.Require(walks.CanExec, "plugin", path); err != nil {
+		return nil, err
+	}
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch action := sym.(type) {
+	case func(string):
+		return action, nil
+	case *func(string):
+		return *action, nil
+	}
+	return nil, fmt.Errorf("hook: plugin %v: %v has type %T, want func(string)", path, PluginSymbol, sym)
+}
+
+// Request is a message sent to an external hook for each walked path.
+type Request struct {
+	Path  string `json:"path"`
+	IsDir bool   `json:"dir"`
+}
+
+// Response is a message an external hook answers each Request with. Empty Error means success.
+type Response struct {
+	Error string `json:"error,omitempty"`
+}
+
+// ErrClosed is returned by an Exec used after Close.
+var ErrClosed = errors.New("hook: hook closed")
+
+// Exec runs actions in an external executable, speaking JSON over stdio:
+// for each path the hook reads one Request line from standard input and writes one Response line to standard output.
+// This lets walk-based tools be extended in any language without recompilation.
+// Exec is safe for concurrent use, requests are sent one at a time.
+type Exec struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	enc *json.Encoder
+	dec *json.Decoder
+
+	// Walker, if not nil, must grant CanExec for calls of the hook. Hooks started by StartExec with a Walker have it set.
+	Walker *walks.Walker
+
+	mu     sync.Mutex
+	closed bool
+	err    error // first error of the hook
+}
+
+// StartExec starts command name with args as an external hook, with the hook's Walker set to w (which may be nil).
+// The command is not started, unless w grants CanExec (see walks' Require). Hook's standard error is passed through to os.Stderr.
+func StartExec(w *walks.Walker, name string, args ...string) (*Exec, error) {
+	if err := w.Require(walks.CanExec, "hook", name); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &Exec{cmd: cmd, in: in, enc: json.NewEncoder(in), dec: json.NewDecoder(bufio.NewReader(out)), Walker: w}, nil
+}
+
+// Call sends path to the hook and waits for its response.
+func (h *Exec) Call(path string, isDir bool) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return ErrClosed
+	}
+	if err := h.Walker.Require(walks.CanExec, "hook", path); err != nil {
+		return h.fail(err)
+	}
+	if err := h.enc.Encode(Request{Path: path, IsDir: isDir}); err != nil {
+		return h.fail(err)
+	}
+	var resp Response
+	if err := h.dec.Decode(&resp); err != nil {
+		return h.fail(err)
+	}
+	if resp.Error != "" {
+		return h.fail(fmt.Errorf("hook: %v: %v: %v", h.cmd.Path, path, resp.Error))
+	}
+	return nil
+}
+
+// fail records err as the hook's error, if it is the first one.
+func (h *Exec) fail(err error) error {
+	if h.err == nil {
+		h.err = err
+	}
+	return err
+}
+
+// FileAction returns an action, that sends each path to the hook as a file. Errors are available with Err.
+func (h *Exec) FileAction() func(string) {
+	return func(path string) { h.Call(path, false) }
+}
+
+// DirAction returns an action, that sends each path to the hook as a directory. Errors are available with Err.
+func (h *Exec) DirAction() func(string) {
+	return func(path string) { h.Call(path, true) }
+}
+
+// Err returns the first error of the hook, if any.
+func (h *Exec) Err() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.err
+}
+
+// Close closes the hook's standard input and waits for it to exit.
+func (h *Exec) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil
+	}
+	h.closed = true
+	h.in.Close()
+	return h.cmd.Wait()
+}
@@ -0,0 +1,46 @@
+package hook
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+
+	"github.com/moledoc/walks"
+)
+
+func TestCapabilities(t *testing.T) {
+	w := walks.New(walks.WithCapabilities(walks.NoCapabilities | walks.CanRead))
+	if _, err := StartExec(w, "true"); !errors.Is(err, walks.ErrCapability) {
+		t.Errorf("StartExec: got error %v, want ErrCapability", err)
+	}
+	if _, err := LoadPlugin(w, "missing.so"); !errors.Is(err, walks.ErrCapability) {
+		t.Errorf("LoadPlugin: got error %v, want ErrCapability", err)
+	}
+}
+
+func TestExec(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	// the hook fails directories and accepts files
+	h, err := StartExec(nil, sh, "-c", `while read -r line; do case "$line" in *'"dir":true'*) echo '{"error":"no dirs"}';; *) echo '{}';; esac; done`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Call("a.txt", false); err != nil {
+		t.Errorf("got error %v, want nil", err)
+	}
+	if err := h.Call("d", true); err == nil {
+		t.Error("got nil error for directory, want one")
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Call("a.txt", false); err != ErrClosed {
+		t.Errorf("got error %v after Close, want ErrClosed", err)
+	}
+	if h.Err() == nil {
+		t.Error("got nil Err, want the directory's error")
+	}
+}
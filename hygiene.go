@@ -0,0 +1,179 @@
+package walks
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// HygieneRule describes which hygiene checks are done on files with given extension.
+type HygieneRule struct {
+	// TrailingWhitespace reports spaces and tabs at the end of lines.
+	TrailingWhitespace bool
+	// FinalNewline reports non-empty files that do not end with a newline.
+	FinalNewline bool
+	// Indent is the expected indentation style: "tab", "space" or "mixed".
+	// "mixed" only reports lines, where indentation contains both tabs and spaces.
+	// Empty string disables indentation checks.
+	Indent string
+	// TabWidth is the number of spaces that equal one tab when fixing indentation.
+	// When it is not positive, 4 is used.
+	TabWidth int
+}
+
+// HygieneIssue is a single hygiene problem found in a file.
+type HygieneIssue struct {
+	Path    string
+	Line    int // line number starting from 1, 0 for problems concerning the whole file
+	Problem string
+	Fixed   bool
+}
+
+func (i HygieneIssue) String() string {
+	if i.Line == 0 {
+		return fmt.Sprintf("%v: %v", i.Path, i.Problem)
+	}
+	return fmt.Sprintf("%v:%v: %v", i.Path, i.Line, i.Problem)
+}
+
+// Hygiene walks recursively given directory structure and checks files for trailing whitespace, missing final newline and tab/space mixing.
+// Rules are looked up by file extension (eg ".go"), key "" is used for files without extension; files without a rule are not checked.
+// Binary files (files containing a NUL byte) are skipped.
+// If fix is true, found issues are fixed in place and reported with Fixed set.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Hygiene call.
+func Hygiene(root string, rules map[string]HygieneRule, fix bool) ([]HygieneIssue, error) {
+	var issues []HygieneIssue
+	var firstErr error
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		rule, ok := rules[filepath.Ext(path)]
+		if !ok {
+			return
+		}
+		found, err := hygieneFile(path, rule, fix)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		issues = append(issues, found...)
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return issues, firstErr
+}
+
+// Hygiene checks files under root like package's Hygiene does, if w grants CanRead (and CanWrite, when fix is true; see Require).
+func (w *Walker) Hygiene(root string, rules map[string]HygieneRule, fix bool) ([]HygieneIssue, error) {
+	caps := CanRead
+	if fix {
+		caps |= CanWrite
+	}
+	if err := w.Require(caps, "hygiene", root); err != nil {
+		return nil, err
+	}
+	return Hygiene(root, rules, fix)
+}
+
+// hygieneFile checks (and fixes, if fix is true) one file according to rule.
+func hygieneFile(path string, rule HygieneRule, fix bool) ([]HygieneIssue, error) {
+	contents, err := readFile(DefaultFS, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(contents) == 0 || bytes.IndexByte(contents, 0) != -1 {
+		return nil, nil
+	}
+	tabWidth := rule.TabWidth
+	if tabWidth <= 0 {
+		tabWidth = 4
+	}
+	var issues []HygieneIssue
+	report := func(line int, problem string) {
+		issues = append(issues, HygieneIssue{Path: path, Line: line, Problem: problem, Fixed: fix})
+	}
+
+	lines := strings.Split(string(contents), "\n")
+	// last element is either empty (file ends with newline) or the unterminated last line.
+	hasFinalNewline := lines[len(lines)-1] == ""
+	if hasFinalNewline {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		crlf := strings.HasSuffix(line, "\r")
+		line = strings.TrimSuffix(line, "\r")
+		if rule.TrailingWhitespace {
+			if trimmed := strings.TrimRight(line, " \t"); trimmed != line {
+				report(i+1, "trailing whitespace")
+				line = trimmed
+			}
+		}
+		if rule.Indent != "" {
+			body := strings.TrimLeft(line, " \t")
+			indent := line[:len(line)-len(body)]
+			hasTab, hasSpace := strings.Contains(indent, "\t"), strings.Contains(indent, " ")
+			switch {
+			case rule.Indent == "tab" && hasSpace:
+				col := indentWidth(indent, tabWidth)
+				if fixed := strings.Repeat("\t", col/tabWidth) + strings.Repeat(" ", col%tabWidth) + body; fixed != line {
+					report(i+1, "space in indentation")
+					line = fixed
+				} else {
+					// spaces narrower than a tab (eg alignment) are kept, so the line cannot be fixed.
+					issues = append(issues, HygieneIssue{Path: path, Line: i + 1, Problem: "space in indentation"})
+				}
+			case rule.Indent == "space" && hasTab:
+				report(i+1, "tab in indentation")
+				line = strings.Repeat(" ", indentWidth(indent, tabWidth)) + body
+			case rule.Indent == "mixed" && hasTab && hasSpace:
+				// mixing is only reported, because preferred style is not known.
+				issues = append(issues, HygieneIssue{Path: path, Line: i + 1, Problem: "mixed tabs and spaces in indentation"})
+			}
+		}
+		if crlf {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	if rule.FinalNewline && !hasFinalNewline {
+		report(0, "missing final newline")
+		hasFinalNewline = true
+	}
+
+	if !fix || len(issues) == 0 {
+		return issues, nil
+	}
+	fixed := strings.Join(lines, "\n")
+	if hasFinalNewline {
+		fixed += "\n"
+	}
+	if fixed == string(contents) {
+		// only issues that cannot be fixed were found; rewriting would just bump the modification time.
+		return issues, nil
+	}
+	info, err := DefaultFS.Stat(path)
+	if err != nil {
+		return issues, err
+	}
+	if err := writable("write", path); err != nil {
+		return issues, err
+	}
+	return issues, os.WriteFile(path, []byte(fixed), info.Mode().Perm())
+}
+
+// indentWidth returns the width of indentation indent in columns, with tabs advancing to the next multiple of tabWidth.
+func indentWidth(indent string, tabWidth int) int {
+	col := 0
+	for _, c := range indent {
+		if c == '\t' {
+			col += tabWidth - col%tabWidth
+		} else {
+			col++
+		}
+	}
+	return col
+}
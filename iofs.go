@@ -0,0 +1,50 @@
+package walks
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+)
+
+// IOFS is an FS reading from an io/fs.FS, such as embed.FS, a zip archive (archive/zip.Reader) or testing/fstest.MapFS,
+// so that walks can traverse them, not just the operating system's filesystem.
+// Paths are cleaned and converted to slash form before use, so that paths under "." (eg "./a/b", or `.\a\b` on Windows, as given by walks) are valid io/fs paths.
+// io/fs has no notion of symbolic links: Lstat is the same as Stat.
+type IOFS struct {
+	FS fs.FS
+}
+
+// Open opens file name of the io/fs.FS.
+func (f IOFS) Open(name string) (fs.File, error) {
+	return f.FS.Open(ioPath(name))
+}
+
+// Stat returns fs.Stat of name.
+func (f IOFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.FS, ioPath(name))
+}
+
+// Lstat returns fs.Stat of name.
+func (f IOFS) Lstat(name string) (fs.FileInfo, error) {
+	return fs.Stat(f.FS, ioPath(name))
+}
+
+// ReadDir returns fs.ReadDir of name.
+func (f IOFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(f.FS, ioPath(name))
+}
+
+// ioPath returns name as an io/fs path.
+func ioPath(name string) string {
+	return path.Clean(filepath.ToSlash(name))
+}
+
+// WalkFS walks concurrently directory structure root of fsys like WalkContext does, with paths in io/fs form (eg "." for the whole fsys).
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before WalkFS call.
+func WalkFS(ctx context.Context, fsys fs.FS, root string, fileAction func(string), dirAction func(string), depth int) error {
+	w := New()
+	w.Depth = depth
+	w.FS = IOFS{FS: fsys}
+	return w.WalkContext(ctx, root, fileAction, dirAction)
+}
@@ -0,0 +1,108 @@
+package walks
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// WalkList reads a list of paths separated by sep (usually '\n' or 0, like the output of `find -print0`) from r
+// and dispatches them to given actions without walking the directories: directories are passed to dirAction and regular files to fileAction.
+// Empty entries and paths matching Ignore are skipped; with '\n' as separator, trailing '\r' is removed from the paths.
+// First error stops reading and is returned.
+func WalkList(r io.Reader, sep byte, fileAction func(string), dirAction func(string)) error {
+	return New().WalkList(r, sep, fileAction, dirAction)
+}
+
+// WalkList dispatches paths listed in r like WalkList does, using Walker's configuration:
+// listed paths are ignored, searched and filtered, passed through middleware (see Use) and counted in Stats like entries of a walk,
+// and errors of statting them are handled by ErrorAction and Errors.
+func (w *Walker) WalkList(r io.Reader, sep byte, fileAction func(string), dirAction func(string)) error {
+	if w.optErr != nil {
+		return w.optErr
+	}
+	defer w.cleanTemp()
+	w.start()
+	defer w.finish()
+	counts := newWalkCounts(w.clock())
+	defer w.record(counts)
+	sink := errorSink{counts: counts}
+	fileEntry, dirEntry := w.wrap(plainAction(fileAction)), w.wrap(plainAction(dirAction))
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, sep); i >= 0 {
+			return i + 1, data[:i], nil
+		}
+		if atEOF && len(data) > 0 {
+			return len(data), data, nil
+		}
+		return 0, nil, nil
+	})
+	for scanner.Scan() {
+		path := scanner.Text()
+		if sep == '\n' {
+			path = strings.TrimSuffix(path, "\r")
+		}
+		if path == "" {
+			continue
+		}
+		if w.ignored(path, false) {
+			counts.ignored++
+			continue
+		}
+		info, err := w.stat(path)
+		if err != nil {
+			if err := w.pathError(&sink, path, err); err == SkipAll {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			continue
+		}
+		if info.IsDir() && w.ignored(path, true) {
+			counts.ignored++
+			continue
+		}
+		var action func(Entry) error
+		switch pathType := info.Mode(); {
+		case pathType.IsDir():
+			w.bus.publish(WalkEvent{Kind: EventDir, Path: path})
+			counts.dirs++
+			action = dirEntry
+		case pathType.IsRegular():
+			w.bus.publish(WalkEvent{Kind: EventFile, Path: path})
+			counts.files++
+			counts.bytes += info.Size()
+			action = fileEntry
+		default:
+			continue
+		}
+		if e := (Entry{Path: path, Depth: -1, info: info}); w.included(e) {
+			if err := action(e); err == SkipAll {
+				return nil
+			} else if err != nil && err != SkipDir {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return sink.err()
+}
+
+// PathWriter returns an action, that writes each path it is given to w followed by sep,
+// so that walks can produce path lists for other tools (or for WalkList).
+// The action is safe to use from concurrent walks; write errors are ignored.
+func PathWriter(w io.Writer, sep byte) func(string) {
+	var mu sync.Mutex
+	return func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, path)
+		w.Write([]byte{sep})
+	}
+}
@@ -0,0 +1,102 @@
+package walks
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrLocked is matched (with errors.Is) by errors of files locked by other processes, see LockedError.
+var ErrLocked = errors.New("walks: file is locked")
+
+// LockedError reports a file locked by another process: an advisory lock (flock or fcntl) on Unix, or a sharing or lock violation on Windows.
+type LockedError struct {
+	Path string
+	// Holders are the process IDs holding the lock, where discoverable (fcntl locks, and flock locks on Linux).
+	Holders []int
+	// Err is the error of the operation failing on the lock, if any.
+	Err error
+}
+
+// Error returns the description of the lock.
+func (e *LockedError) Error() string {
+	msg := "walks: " + e.Path + " is locked"
+	if len(e.Holders) > 0 {
+		pids := make([]string, len(e.Holders))
+		for i, pid := range e.Holders {
+			pids[i] = strconv.Itoa(pid)
+		}
+		msg += " by process " + strings.Join(pids, ", ")
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Is reports whether target is ErrLocked.
+func (e *LockedError) Is(target error) bool {
+	return target == ErrLocked
+}
+
+// Unwrap returns Err.
+func (e *LockedError) Unwrap() error {
+	return e.Err
+}
+
+// CheckLock returns a *LockedError, if file path is locked by another process, or nil otherwise (also when the file cannot be examined).
+// Advisory locks do not stop other processes from changing a file on Unix, so mutating actions check them explicitly;
+// probing a flock lock takes and releases it at once, which may make a concurrent non-blocking lock attempt of another process fail.
+// On Unix systems other than Linux, fcntl locks are probed with F_GETLK on a descriptor of CheckLock's own, and closing it
+// RELEASES ALL FCNTL LOCKS THE CALLING PROCESS HOLDS ON THE FILE (POSIX semantics): do not check files the process itself locks with fcntl there.
+// Linux probes with an open file description lock, that has no such effect; fcntl locks of the calling process are reported there too,
+// and holders of open file description locks are not known.
+func CheckLock(path string) error {
+	if locked, holders := fileLocked(path); locked {
+		return &LockedError{Path: path, Holders: holders}
+	}
+	return nil
+}
+
+// LockPolicy controls what mutating actions (eg Remover) do with files locked by other processes, instead of failing the whole batch.
+// Zero value fails on locked files without retrying.
+type LockPolicy struct {
+	// Retries is the number of times an operation on a locked file is retried, waiting Backoff (doubled after each retry) in between.
+	Retries int
+	Backoff time.Duration
+	// Skip makes locked files (still locked after the retries) be skipped, instead of failing the operation.
+	Skip bool
+	// Report, if not nil, is called with each file, that stays locked after the retries.
+	Report func(*LockedError)
+}
+
+// Do runs op on file path, unless path is locked by another process or op fails on a lock, in which case it is retried and finally
+// skipped or failed according to the policy.
+func (p LockPolicy) Do(path string, op func() error) error {
+	backoff := p.Backoff
+	for attempt := 0; ; attempt++ {
+		err := CheckLock(path)
+		if err == nil {
+			if err = op(); err != nil && isLockError(err) {
+				err = &LockedError{Path: path, Err: err}
+			}
+		}
+		var locked *LockedError
+		if !errors.As(err, &locked) {
+			return err
+		}
+		if attempt < p.Retries {
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		if p.Report != nil {
+			p.Report(locked)
+		}
+		if p.Skip {
+			return nil
+		}
+		return err
+	}
+}
@@ -0,0 +1,10 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+// +build darwin dragonfly freebsd netbsd openbsd
+
+package walks
+
+import "syscall"
+
+// getLock is F_GETLK, as there are no open file description locks on this platform.
+// Beware: closing the probed file releases all fcntl locks the process holds on it, see CheckLock.
+const getLock = syscall.F_GETLK
@@ -0,0 +1,5 @@
+package walks
+
+// getLock is F_OFD_GETLK: the probe's lock belongs to its open file description, not to the process,
+// so closing the probed file does not release the fcntl locks the process holds on it through other descriptors.
+const getLock = 36
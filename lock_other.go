@@ -0,0 +1,14 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!windows
+
+package walks
+
+// fileLocked reports false, locks are not detected on this platform.
+func fileLocked(path string) (bool, []int) {
+	return false, nil
+}
+
+// isLockError reports false, locks are not detected on this platform.
+func isLockError(err error) bool {
+	return false
+}
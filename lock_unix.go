@@ -0,0 +1,77 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package walks
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// fileLocked reports whether file path holds an advisory lock of another process (flock or fcntl), with the holders where discoverable.
+func fileLocked(path string) (bool, []int) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, nil
+	}
+	defer f.Close()
+	fd := int(f.Fd())
+	var holders []int
+	locked := false
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: 0}
+	if err := syscall.FcntlFlock(uintptr(fd), getLock, &lk); err == nil && lk.Type != syscall.F_UNLCK {
+		locked = true
+		// holders of open file description locks are not known (reported as -1)
+		if lk.Pid > 0 {
+			holders = append(holders, int(lk.Pid))
+		}
+	}
+	if err := syscall.Flock(fd, syscall.LOCK_EX|syscall.LOCK_NB); err == syscall.EWOULDBLOCK {
+		locked = true
+		if info, err := f.Stat(); err == nil {
+			holders = append(holders, flockHolders(info)...)
+		}
+	} else if err == nil {
+		syscall.Flock(fd, syscall.LOCK_UN)
+	}
+	return locked, holders
+}
+
+// flockHolders returns the processes holding flock locks on the file described by info, as listed in /proc/locks (Linux only).
+func flockHolders(info os.FileInfo) []int {
+	dev, ino, ok := fileID(info)
+	if !ok {
+		return nil
+	}
+	f, err := os.Open("/proc/locks")
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	major, minor := (dev>>8)&0xfff|(dev>>32)&^0xfff, dev&0xff|(dev>>12)&^0xff
+	id := fmt.Sprintf("%02x:%02x:%d", major, minor, ino)
+	var pids []int
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// eg "1: FLOCK  ADVISORY  WRITE 1234 08:01:123456 0 EOF"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || fields[1] != "FLOCK" {
+			continue
+		}
+		if fields[5] == id {
+			if pid, err := strconv.Atoi(fields[4]); err == nil {
+				pids = append(pids, pid)
+			}
+		}
+	}
+	return pids
+}
+
+// isLockError reports whether err is a failure because of a lock; operations are not blocked by advisory locks on Unix.
+func isLockError(err error) bool {
+	return false
+}
@@ -0,0 +1,35 @@
+//go:build windows
+// +build windows
+
+package walks
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Windows error codes of files locked by other processes.
+const (
+	errorSharingViolation syscall.Errno = 32
+	errorLockViolation    syscall.Errno = 33
+)
+
+// fileLocked reports whether file path is opened by another process without sharing, by opening it without sharing itself.
+// Holders are not discoverable.
+func fileLocked(path string) (bool, []int) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return false, nil
+	}
+	h, err := syscall.CreateFile(p, syscall.GENERIC_READ, 0, nil, syscall.OPEN_EXISTING, syscall.FILE_ATTRIBUTE_NORMAL, 0)
+	if err != nil {
+		return isLockError(err), nil
+	}
+	syscall.CloseHandle(h)
+	return false, nil
+}
+
+// isLockError reports whether err is a sharing or lock violation.
+func isLockError(err error) bool {
+	return errors.Is(err, errorSharingViolation) || errors.Is(err, errorLockViolation)
+}
@@ -0,0 +1,74 @@
+package walks
+
+import (
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// compressionSuffixes are extensions of compressed rotated logs.
+var compressionSuffixes = []string{".gz", ".bz2", ".xz", ".zst", ".lz4", ".z", ".Z", ".zip"}
+
+// rotationSuffix matches numeric (app.log.1) and date (app.log-20230101, app.log.2023-01-01) rotation suffixes.
+var rotationSuffix = regexp.MustCompile(`[.-](\d+|\d{4}-?\d{2}-?\d{2}([._-]?\d{2,6})?)$`)
+
+// rotationInfix matches date before the extension (app-2023-01-01.log, app.20230101.log).
+var rotationInfix = regexp.MustCompile(`[.-]\d{4}-?\d{2}-?\d{2}([._-]?\d{2,6})?(\.[^.]+)$`)
+
+// LogFamily returns the name of the logical file, that rotated, numbered or compressed variant name belongs to:
+// "app.log", "app.log.1", "app.log.2.gz", "app.log-20230101" and "app-2023-01-01.log" all belong to "app.log".
+func LogFamily(name string) string {
+	for _, suffix := range compressionSuffixes {
+		if strings.HasSuffix(name, suffix) && len(name) > len(suffix) {
+			name = name[:len(name)-len(suffix)]
+			break
+		}
+	}
+	if family := rotationSuffix.ReplaceAllString(name, ""); family != "" {
+		name = family
+	}
+	if family := rotationInfix.ReplaceAllString(name, "$2"); family != "" {
+		name = family
+	}
+	return name
+}
+
+// LogGroup returns the family of file path (see LogFamily) together with its directory.
+// It can be used as RetentionRule's Group to keep the last N rotations of each log.
+func LogGroup(path string) string {
+	return filepath.Join(filepath.Dir(path), LogFamily(filepath.Base(path)))
+}
+
+// GroupLogs walks recursively given directory structure and clusters files into log families (see LogGroup).
+// Returned map is keyed by the family path, with member files sorted newest first by modification time; families with a single member are included as well.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before GroupLogs call.
+func GroupLogs(root string) (map[string][]string, error) {
+	families := make(map[string][]string)
+	mtimes := make(map[string]int64)
+	var firstErr error
+	fileAction := func(path string) {
+		info, err := DefaultFS.Stat(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		mtimes[path] = info.ModTime().UnixNano()
+		family := LogGroup(path)
+		families[family] = append(families[family], path)
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	for _, members := range families {
+		sort.Slice(members, func(i, j int) bool {
+			if mtimes[members[i]] != mtimes[members[j]] {
+				return mtimes[members[i]] > mtimes[members[j]]
+			}
+			return members[i] < members[j]
+		})
+	}
+	return families, firstErr
+}
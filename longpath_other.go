@@ -0,0 +1,9 @@
+//go:build !windows
+// +build !windows
+
+package walks
+
+// longPath returns abs, paths are not limited in length on this platform.
+func longPath(abs string) string {
+	return abs
+}
@@ -0,0 +1,17 @@
+//go:build windows
+// +build windows
+
+package walks
+
+import "strings"
+
+// longPath returns absolute path abs in `\\?\` form.
+func longPath(abs string) string {
+	switch {
+	case strings.HasPrefix(abs, `\\?\`), strings.HasPrefix(abs, `\\.\`):
+		return abs
+	case strings.HasPrefix(abs, `\\`):
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,78 @@
+package walks
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+)
+
+// MerkleHash computes a deterministic SHA-256 hash of directory root from the hashes and names of its contents, recursively.
+// Files are hashed by their contents, symlinks by their target and directories by the sorted names, types and hashes of their children,
+// so two subtrees have equal hashes exactly when they have the same structure and contents (modification times and permissions are not considered).
+// If sink is not nil, it is called with the hash of every file and directory as soon as it is computed (children before their parent);
+// an error returned by sink stops hashing and is returned.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before MerkleHash call.
+func MerkleHash(root string, sink func(path string, sum []byte) error) ([]byte, error) {
+	if sink == nil {
+		sink = func(string, []byte) error { return nil }
+	}
+	return merkleDir(root, sink)
+}
+
+// MerkleHash hashes the tree under root like package's MerkleHash does, if w grants CanRead (see Require).
+func (w *Walker) MerkleHash(root string, sink func(path string, sum []byte) error) ([]byte, error) {
+	if err := w.Require(CanRead, "hash", root); err != nil {
+		return nil, err
+	}
+	return MerkleHash(root, sink)
+}
+
+// merkleDir returns the hash of directory dir, passing the hashes of its contents to sink.
+func merkleDir(dir string, sink func(string, []byte) error) ([]byte, error) {
+	subpaths, err := DefaultFS.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	for _, path := range subpaths {
+		pathName := filepath.Join(dir, path.Name())
+		if ignoredPath(pathName, path.IsDir()) {
+			continue
+		}
+		var kind byte
+		var sum []byte
+		switch pathType := path.Type(); {
+		case pathType.IsDir():
+			kind = 'd'
+			sum, err = merkleDir(pathName, sink)
+		case pathType.IsRegular():
+			kind = 'f'
+			sum, err = hashFile(pathName)
+		case pathType&os.ModeSymlink != 0:
+			kind = 'l'
+			var target string
+			target, err = readLink(DefaultFS, pathName)
+			s := sha256.Sum256([]byte(target))
+			sum = s[:]
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if kind != 'd' {
+			if err := sink(pathName, sum); err != nil {
+				return nil, err
+			}
+		}
+		h.Write([]byte{kind})
+		h.Write([]byte(path.Name()))
+		h.Write([]byte{0})
+		h.Write(sum)
+	}
+	sum := h.Sum(nil)
+	if err := sink(dir, sum); err != nil {
+		return nil, err
+	}
+	return sum, nil
+}
@@ -0,0 +1,31 @@
+package walks
+
+// Middleware wraps an action with cross-cutting behavior (timing, logging, retries, sampling, ...), returning the wrapped action.
+// The wrapped action decides whether, when and how many times next is called, and what becomes of its error (see WalkErr).
+// Actions that cannot fail are seen by middleware as returning nil.
+type Middleware func(next func(path string) error) func(path string) error
+
+// Use adds middleware to the chain applied to all actions of walks done with w: fileAction, dirAction, OtherAction, SymlinkAction and DirActionPost.
+// Middleware is applied in onion style: the first one added is the outermost, seeing each call first and its completion last.
+func (w *Walker) Use(middleware ...Middleware) {
+	w.middleware = append(w.middleware, middleware...)
+}
+
+// wrap applies w's middleware chain to action, cloning the entries passed to it if w retains them (see RetainEntries).
+// Middleware sees the entry's path; the entry is passed on to action as it is.
+func (w *Walker) wrap(action func(Entry) error) func(Entry) error {
+	if w.RetainEntries {
+		retained := action
+		action = func(e Entry) error { return retained(e.Clone()) }
+	}
+	if len(w.middleware) == 0 {
+		return action
+	}
+	return func(e Entry) error {
+		next := func(string) error { return action(e) }
+		for i := len(w.middleware) - 1; i >= 0; i-- {
+			next = w.middleware[i](next)
+		}
+		return next(e.Path)
+	}
+}
@@ -0,0 +1,46 @@
+package walks
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// MirrorDirs recreates the directory skeleton of src under dst, without copying any files.
+// Created directories get permissions perm.
+// If mapper is not nil, each directory path relative to src is passed through it and the result is used as the path relative to dst.
+// Directories can be ignored by setting Ignore value with SetIgnore function or manually before MirrorDirs call.
+func MirrorDirs(src string, dst string, perm os.FileMode, mapper PathMapper) error {
+	if err := writable("mkdir", dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, perm); err != nil {
+		return err
+	}
+	var firstErr error
+	dirAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		if mapper != nil {
+			rel = mapper(rel)
+		}
+		firstErr = os.MkdirAll(filepath.Join(dst, rel), perm)
+	}
+	if _, err := WalkLinearE(src, func(string) {}, dirAction, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// MirrorDirs recreates the directory skeleton of src under dst like package's MirrorDirs does, if w grants CanWrite (see Require).
+func (w *Walker) MirrorDirs(src string, dst string, perm os.FileMode, mapper PathMapper) error {
+	if err := w.Require(CanWrite, "mkdir", dst); err != nil {
+		return err
+	}
+	return MirrorDirs(src, dst, perm, mapper)
+}
@@ -0,0 +1,137 @@
+package walks
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// mlocateMagic starts every mlocate database.
+const mlocateMagic = "\x00mlocate"
+
+// mlocate entry types.
+const (
+	mlocateFile byte = iota
+	mlocateDir
+	mlocateEnd
+)
+
+// ErrMlocateFormat is returned by ReadMlocate for input that is not an mlocate database.
+var ErrMlocateFormat = errors.New("walks: invalid mlocate database")
+
+// WriteMlocate writes snap to w in mlocate database format (version 0), as written by updatedb,
+// so that `locate -d <file>` can search walks-generated indexes; plocate users can convert it with plocate-build.
+// Only directories and their entries are recorded, as mlocate stores nothing else.
+func WriteMlocate(w io.Writer, snap *Snapshot) error {
+	bw := bufio.NewWriter(w)
+	root, err := filepath.Abs(snap.Root)
+	if err != nil {
+		return err
+	}
+	// header: magic, configuration block size, version, visibility flag, padding and root
+	bw.WriteString(mlocateMagic)
+	binary.Write(bw, binary.BigEndian, uint32(0))
+	bw.Write([]byte{0, 0, 0, 0})
+	bw.WriteString(root)
+	bw.WriteByte(0)
+	for _, path := range snap.Paths() {
+		dir := snap.Entries[path]
+		if !dir.IsDir() {
+			continue
+		}
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return err
+		}
+		binary.Write(bw, binary.BigEndian, uint64(dir.ModTime.Unix()))
+		binary.Write(bw, binary.BigEndian, uint32(dir.ModTime.Nanosecond()))
+		bw.Write([]byte{0, 0, 0, 0})
+		bw.WriteString(abs)
+		bw.WriteByte(0)
+		for _, child := range snap.Children(path) {
+			if child.IsDir() {
+				bw.WriteByte(mlocateDir)
+			} else {
+				bw.WriteByte(mlocateFile)
+			}
+			bw.WriteString(filepath.Base(child.Path))
+			bw.WriteByte(0)
+		}
+		bw.WriteByte(mlocateEnd)
+	}
+	return bw.Flush()
+}
+
+// ReadMlocate reads an mlocate database from r into a Snapshot.
+// Database records no sizes nor file modification times, so entries only have Mode (os.ModeDir or 0) and directories their recorded time as ModTime.
+func ReadMlocate(r io.Reader) (*Snapshot, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(mlocateMagic)+8)
+	if _, err := io.ReadFull(br, header); err != nil || string(header[:len(mlocateMagic)]) != mlocateMagic {
+		return nil, ErrMlocateFormat
+	}
+	confSize := binary.BigEndian.Uint32(header[len(mlocateMagic):])
+	root, err := readCString(br)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, br, int64(confSize)); err != nil {
+		return nil, ErrMlocateFormat
+	}
+	snap := &Snapshot{Root: root, Entries: make(map[string]SnapshotEntry), children: make(map[string][]string)}
+	dirHeader := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(br, dirHeader); err == io.EOF {
+			return snap, nil
+		} else if err != nil {
+			return nil, ErrMlocateFormat
+		}
+		mtime := time.Unix(int64(binary.BigEndian.Uint64(dirHeader)), int64(binary.BigEndian.Uint32(dirHeader[8:])))
+		dir, err := readCString(br)
+		if err != nil {
+			return nil, err
+		}
+		snap.Entries[dir] = SnapshotEntry{Path: dir, Mode: os.ModeDir, ModTime: mtime}
+		if mtime.After(snap.Taken) {
+			snap.Taken = mtime
+		}
+		var names []string
+		for {
+			kind, err := br.ReadByte()
+			if err != nil {
+				return nil, ErrMlocateFormat
+			}
+			if kind == mlocateEnd {
+				break
+			}
+			name, err := readCString(br)
+			if err != nil {
+				return nil, err
+			}
+			path := filepath.Join(dir, name)
+			if _, ok := snap.Entries[path]; !ok {
+				var mode os.FileMode
+				if kind == mlocateDir {
+					mode = os.ModeDir
+				}
+				snap.Entries[path] = SnapshotEntry{Path: path, Mode: mode}
+			}
+			names = append(names, name)
+		}
+		snap.children[dir] = names
+	}
+}
+
+// readCString reads a NUL terminated string.
+func readCString(br *bufio.Reader) (string, error) {
+	s, err := br.ReadBytes(0)
+	if err != nil {
+		return "", ErrMlocateFormat
+	}
+	return string(bytes.TrimSuffix(s, []byte{0})), nil
+}
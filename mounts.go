@@ -0,0 +1,171 @@
+package walks
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MountPolicy controls how Walker treats automount points and network filesystems mounted in the walked tree, which can make a walk
+// trigger mounts or hang for minutes on a dead NFS or SMB server (eg when walking a developer workstation's / or /home).
+// They are recognized by the mount table, before they are touched; detection works on Linux only (elsewhere all policies walk them).
+// Directories not descended into are reported to Guard with ErrAutomount, ErrNetworkMount or ErrUnreachable;
+// unlike with other Guard reasons, they are not passed to dirAction, as even statting a dead mount can hang.
+type MountPolicy int
+
+const (
+	// MountsWalk walks automount points and network filesystems like other directories. This is the default.
+	MountsWalk MountPolicy = iota
+	// MountsSkip does not descend into automount points and network filesystems.
+	MountsSkip
+	// MountsTimeout probes automount points and network filesystems by listing them, descending into them only if they answer within MountTimeout.
+	MountsTimeout
+	// MountsFail probes like MountsTimeout, but fails the walk with ErrUnreachable, when a filesystem does not answer.
+	MountsFail
+)
+
+// DefaultMountTimeout is the time an automount point or a network filesystem must answer within under MountsTimeout and MountsFail,
+// when Walker's MountTimeout is 0.
+const DefaultMountTimeout = 5 * time.Second
+
+// ErrAutomount is reported to Walker's Guard for an automount point skipped under MountsSkip.
+var ErrAutomount = errors.New("walks: automount point")
+
+// ErrNetworkMount is reported to Walker's Guard for a network filesystem skipped under MountsSkip.
+var ErrNetworkMount = errors.New("walks: network filesystem")
+
+// ErrUnreachable is reported to Walker's Guard (or fails the walk, under MountsFail) for a filesystem, that did not answer a probe in time,
+// or failed it (then wrapping the error of the probe).
+var ErrUnreachable = errors.New("walks: filesystem unreachable")
+
+// networkFS are the mount table types of network filesystems.
+var networkFS = map[string]bool{
+	"nfs":            true,
+	"nfs4":           true,
+	"cifs":           true,
+	"smb3":           true,
+	"smbfs":          true,
+	"afs":            true,
+	"ceph":           true,
+	"lustre":         true,
+	"gpfs":           true,
+	"ncpfs":          true,
+	"fuse.sshfs":     true,
+	"fuse.rclone":    true,
+	"fuse.glusterfs": true,
+	"fuse.s3fs":      true,
+}
+
+// mountTable is the automount points and network filesystems under a walked root, by absolute path.
+type mountTable struct {
+	root    string
+	absRoot string
+	points  map[string]string // filesystem type by mount point
+}
+
+// newMountTable returns the mount table for a walk of root according to Walker's Mounts, nil if there is nothing to check.
+func (w *Walker) newMountTable(root string) *mountTable {
+	if w.Mounts == MountsWalk {
+		return nil
+	}
+	if _, ok := w.fsys().(OSFS); !ok {
+		return nil
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil
+	}
+	points := make(map[string]string)
+	for point, typ := range readMounts() {
+		if typ == "autofs" || networkFS[typ] {
+			if rel, err := filepath.Rel(abs, point); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				points[point] = typ
+			}
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	return &mountTable{root: root, absRoot: abs, points: points}
+}
+
+// has reports whether path is one of the mount points of the table; nil table has none.
+func (t *mountTable) has(path string) bool {
+	return t.lookup(path) != ""
+}
+
+// lookup returns the filesystem type of mount point path, or "" if it is not in the table.
+func (t *mountTable) lookup(path string) string {
+	if t == nil || !strings.HasPrefix(path, t.root) {
+		return ""
+	}
+	return t.points[filepath.Join(t.absRoot, path[len(t.root):])]
+}
+
+// mounts applies Walker's Mounts policy to directory path, an entry of node, returning the reason not to descend into it (nil to descend).
+func (w *Walker) mounts(node *dirNode, path string) error {
+	typ := node.mounts.lookup(path)
+	if typ == "" {
+		return nil
+	}
+	if w.Mounts == MountsSkip {
+		if typ == "autofs" {
+			return ErrAutomount
+		}
+		return ErrNetworkMount
+	}
+	return w.probe(path)
+}
+
+// probe lists directory path, returning ErrUnreachable if it does not answer within Walker's MountTimeout.
+// A listing that hangs in the kernel cannot be cancelled: its goroutine is left behind until the filesystem answers or the mount times out.
+func (w *Walker) probe(path string) error {
+	timeout := w.MountTimeout
+	if timeout <= 0 {
+		timeout = DefaultMountTimeout
+	}
+	done := make(chan error, 1)
+	go func() {
+		f, err := w.fsys().Open(path)
+		if err != nil {
+			done <- err
+			return
+		}
+		defer f.Close()
+		if d, ok := f.(fs.ReadDirFile); ok {
+			if _, err := d.ReadDir(1); err != nil && err != io.EOF {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnreachable, err)
+		}
+		return nil
+	case <-timer.C:
+		return ErrUnreachable
+	}
+}
+
+// mountGuard reports directory path, not descended into because of err of mounts, to Guard.
+// It returns the error to fail the walk with under MountsFail.
+func (w *Walker) mountGuard(path string, err error) error {
+	if w.Mounts == MountsFail && errors.Is(err, ErrUnreachable) {
+		return &fs.PathError{Op: "walk", Path: path, Err: err}
+	}
+	w.bus.publish(WalkEvent{Kind: EventGuard, Path: path, Err: err})
+	if w.Guard != nil {
+		w.Guard(path, err)
+	}
+	return nil
+}
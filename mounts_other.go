@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+// readMounts returns no mounts, the mount table is only read on Linux.
+func readMounts() map[string]string {
+	return nil
+}
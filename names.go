@@ -0,0 +1,65 @@
+package walks
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Paths are handled as raw bytes everywhere in walks: names with newlines, control characters or invalid UTF-8 are neither altered nor rejected,
+// so they can be passed back to the filesystem as they are. Only text outputs need to escape them, see PathEncoder.
+
+// HasControl reports whether name contains ASCII or Unicode control characters (including newline and tab).
+func HasControl(name string) bool {
+	for _, r := range name {
+		if r != utf8.RuneError && unicode.IsControl(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasNewline reports whether name contains a newline or carriage return, which breaks newline-separated path lists.
+func HasNewline(name string) bool {
+	return strings.ContainsAny(name, "\n\r")
+}
+
+// HasBidi reports whether name contains Unicode bidirectional control characters, which can make a name display differently from what it is (eg "exe.txt" being "txt.exe").
+func HasBidi(name string) bool {
+	return strings.ContainsAny(name, "\u202a\u202b\u202c\u202d\u202e\u2066\u2067\u2068\u2069\u200e\u200f")
+}
+
+// HostileName reports whether name contains anything likely to confuse tools and people reading it:
+// control characters, invalid UTF-8, bidirectional controls, leading dash (taken as an option by commands) or leading/trailing spaces.
+func HostileName(name string) bool {
+	return HasControl(name) || !utf8.ValidString(name) || HasBidi(name) ||
+		strings.HasPrefix(name, "-") || strings.TrimSpace(name) != name
+}
+
+// PathEncoder converts a path for a text output.
+type PathEncoder func(path string) string
+
+// EscapeC is a PathEncoder that escapes control characters, invalid UTF-8 and backslashes with Go/C style escapes (\n, \t, \x80, \u202e, \\),
+// leaving other characters as they are; the result is always valid UTF-8 and always a single line.
+func EscapeC(path string) string {
+	if !HostileName(path) && !strings.Contains(path, `\`) {
+		return path
+	}
+	quoted := strconv.Quote(path)
+	// strconv.Quote also escapes quotes, which are harmless in a path.
+	return strings.Replace(quoted[1:len(quoted)-1], `\"`, `"`, -1)
+}
+
+// EncodedPathWriter returns an action, that writes each path it is given to w, encoded with enc and followed by a newline.
+// The action is safe to use from concurrent walks; write errors are ignored.
+func EncodedPathWriter(w io.Writer, enc PathEncoder) func(string) {
+	var mu sync.Mutex
+	return func(path string) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, enc(path)+"\n")
+	}
+}
@@ -0,0 +1,150 @@
+package walks
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// ncduEntry is the information object of an entry in ncdu's JSON export format.
+type ncduEntry struct {
+	Name   string `json:"name"`
+	Asize  int64  `json:"asize,omitempty"`
+	Dsize  int64  `json:"dsize,omitempty"`
+	Mtime  int64  `json:"mtime,omitempty"`
+	Notreg bool   `json:"notreg,omitempty"`
+	// Excluded is set by ncdu for entries it did not scan (eg "pattern" or "otherfs").
+	Excluded string `json:"excluded,omitempty"`
+}
+
+// ErrNcduFormat is returned by ReadNcdu for input that is not an ncdu JSON export.
+var ErrNcduFormat = errors.New("walks: invalid ncdu export")
+
+// WriteNcdu writes snap to w in ncdu's JSON export format (version 1.2), so that it can be browsed with `ncdu -f <file>`.
+// Snapshots do not record disk usage, so apparent size is written as disk usage as well.
+func WriteNcdu(w io.Writer, snap *Snapshot) error {
+	bw := bufio.NewWriter(w)
+	header, err := json.Marshal(map[string]interface{}{"progname": "walks", "progver": "1", "timestamp": snap.Taken.Unix()})
+	if err != nil {
+		return err
+	}
+	bw.WriteString("[1,2,")
+	bw.Write(header)
+	var write func(path string, name string) error
+	write = func(path string, name string) error {
+		entry := snap.Entries[path]
+		info := ncduEntry{Name: name, Mtime: entry.ModTime.Unix()}
+		if entry.Mode.IsRegular() {
+			info.Asize, info.Dsize = entry.Size, entry.Size
+		} else if !entry.IsDir() {
+			info.Notreg = true
+		}
+		obj, err := json.Marshal(info)
+		if err != nil {
+			return err
+		}
+		bw.WriteByte(',')
+		if !entry.IsDir() {
+			bw.Write(obj)
+			return nil
+		}
+		bw.WriteByte('[')
+		bw.Write(obj)
+		for _, child := range snap.Children(path) {
+			if err := write(child.Path, filepath.Base(child.Path)); err != nil {
+				return err
+			}
+		}
+		bw.WriteByte(']')
+		return nil
+	}
+	root, err := filepath.Abs(snap.Root)
+	if err != nil {
+		return err
+	}
+	if err := write(snap.Root, root); err != nil {
+		return err
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// ReadNcdu reads ncdu's JSON export (as written by `ncdu -o <file>` or WriteNcdu) from r into a Snapshot,
+// so that ncdu scans can be compared with Diff like any other snapshot.
+// Entries ncdu excluded from its scan are left out; modification times are only known for exports made with extended information (`ncdu -e`).
+func ReadNcdu(r io.Reader) (*Snapshot, error) {
+	var dump []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&dump); err != nil || len(dump) < 4 {
+		return nil, ErrNcduFormat
+	}
+	var major int
+	var meta struct {
+		Timestamp int64 `json:"timestamp"`
+	}
+	if json.Unmarshal(dump[0], &major) != nil || major != 1 || json.Unmarshal(dump[2], &meta) != nil {
+		return nil, ErrNcduFormat
+	}
+	snap := &Snapshot{Taken: time.Unix(meta.Timestamp, 0), Entries: make(map[string]SnapshotEntry), children: make(map[string][]string)}
+	if _, err := snap.readNcdu(dump[3], ""); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// readNcdu records ncdu entry raw (an information object, or an array with one followed by the contents for a directory)
+// in directory dir ("" for the root), returning its name or "" if it is excluded.
+func (s *Snapshot) readNcdu(raw json.RawMessage, dir string) (string, error) {
+	var contents []json.RawMessage
+	isDir := len(raw) > 0 && raw[0] == '['
+	if isDir {
+		if err := json.Unmarshal(raw, &contents); err != nil || len(contents) == 0 {
+			return "", ErrNcduFormat
+		}
+		raw, contents = contents[0], contents[1:]
+	}
+	var info ncduEntry
+	if err := json.Unmarshal(raw, &info); err != nil || info.Name == "" {
+		return "", ErrNcduFormat
+	}
+	if info.Excluded != "" {
+		return "", nil
+	}
+	path := info.Name
+	if dir == "" {
+		s.Root = path
+	} else {
+		path = filepath.Join(dir, info.Name)
+	}
+	entry := SnapshotEntry{Path: path, Size: info.Asize}
+	if info.Mtime != 0 {
+		entry.ModTime = time.Unix(info.Mtime, 0)
+	}
+	switch {
+	case isDir:
+		entry.Mode, entry.Size = os.ModeDir, 0
+	case info.Notreg:
+		entry.Mode = os.ModeIrregular
+	}
+	s.Entries[path] = entry
+	if !isDir {
+		return info.Name, nil
+	}
+	names := []string{}
+	for _, child := range contents {
+		name, err := s.readNcdu(child, path)
+		if err != nil {
+			return "", err
+		}
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	s.children[path] = names
+	return info.Name, nil
+}
@@ -0,0 +1,45 @@
+package walks
+
+// NestedPolicy controls walks started with a Walker while another of its walks is running,
+// typically from inside a fileAction or dirAction (eg to descend into an archive or a resolved symlink target).
+// Every walk waits for its own goroutines only, so nested walks never deadlock; the policy decides how they share the Walker's Quota.
+type NestedPolicy int
+
+const (
+	// NestedIsolated gives each walk its own Quota limits and worker pool.
+	NestedIsolated NestedPolicy = iota
+	// NestedShared makes nested walks count towards the Quota of the outermost running walk: its entries, rate and time.
+	// Nested walk lists its directories in the goroutine of the action that started it, using the action's worker,
+	// so that MaxWorkers holds for the walks together and the nested walk cannot wait for workers held by the actions waiting for it.
+	NestedShared
+)
+
+// limiter returns the limiter for a new walk of w, and whether it is shared with an enclosing walk.
+func (w *Walker) limiter() (*limiter, bool) {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	if w.Nested == NestedShared && w.pool != nil {
+		return w.pool, true
+	}
+	lim := newLimiter(w.Quota, w.clock())
+	if w.pool == nil {
+		w.pool = lim
+	}
+	return lim, false
+}
+
+// release records the usage of a finished walk's own limiter (see Usage).
+func (w *Walker) release(lim *limiter, shared bool) {
+	if shared {
+		return
+	}
+	usage := lim.finish()
+	w.usageMu.Lock()
+	w.usage = usage
+	w.usageMu.Unlock()
+	w.lifeMu.Lock()
+	if w.pool == lim {
+		w.pool = nil
+	}
+	w.lifeMu.Unlock()
+}
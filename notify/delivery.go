@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPDelivery delivers reports as plain text emails.
+type SMTPDelivery struct {
+	// Addr is the SMTP server's address, eg "mail.example.com:587".
+	Addr string
+	// Auth authenticates with the server, if not nil (eg smtp.PlainAuth).
+	Auth smtp.Auth
+	From string
+	To   []string
+}
+
+// Deliver sends body as an email with subject.
+// Line breaks in subject are replaced with spaces, and it is encoded as needed (RFC 2047); From or To containing line breaks is an error.
+func (d SMTPDelivery) Deliver(subject string, body string) error {
+	for _, addr := range append([]string{d.From}, d.To...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("notify: line break in email address %q", addr)
+		}
+	}
+	subject = mime.QEncoding.Encode("utf-8", strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(subject))
+	msg := fmt.Sprintf("From: %v\r\nTo: %v\r\nSubject: %v\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%v",
+		d.From, strings.Join(d.To, ", "), subject, strings.Replace(body, "\n", "\r\n", -1))
+	return smtp.SendMail(d.Addr, d.Auth, d.From, d.To, []byte(msg))
+}
+
+// SlackDelivery delivers reports to a Slack incoming webhook.
+type SlackDelivery struct {
+	WebhookURL string
+	// Client sends the requests; nil means http.DefaultClient.
+	Client *http.Client
+}
+
+// Deliver posts subject and body as one message, with body in a preformatted block.
+func (d SlackDelivery) Deliver(subject string, body string) error {
+	payload, err := json.Marshal(map[string]string{"text": "*" + subject + "*\n```\n" + body + "```"})
+	if err != nil {
+		return err
+	}
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(d.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify: slack webhook: %v", resp.Status)
+	}
+	return nil
+}
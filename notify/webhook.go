@@ -0,0 +1,141 @@
+/*
+Package notify sends walk results to external systems: batched webhooks and report deliveries (see walks.Reporter) by email and Slack.
+
+It is a package of its own, so that programs not sending notifications do not link packages net/http and net/smtp.
+*/
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/moledoc/walks"
+)
+
+// SignatureHeader is the HTTP header carrying the HMAC-SHA256 signature of a Webhook's request body, as "sha256=<hex>".
+const SignatureHeader = "X-Walks-Signature"
+
+// Webhook is a walks.Sink, that POSTs batches of walked paths, diff and watch events or alerts to a URL as JSON arrays,
+// so that they can feed external systems without custom glue. Webhook is safe for concurrent use.
+type Webhook struct {
+	// URL receives the batches.
+	URL string
+	// Secret, if not empty, signs each request body with HMAC-SHA256 in SignatureHeader.
+	Secret []byte
+	// BatchSize is the number of items sent in one request; 0 means 100.
+	BatchSize int
+	// Retries is the number of times a failed request is retried, waiting Backoff (doubled after each retry) between tries.
+	Retries int
+	Backoff time.Duration
+	// Client sends the requests; nil means http.DefaultClient.
+	Client *http.Client
+
+	mu    sync.Mutex
+	batch []interface{}
+}
+
+// NewWebhook returns a Webhook posting to url, signing requests with secret (if not empty) and retrying failed requests 3 times.
+func NewWebhook(url string, secret []byte) *Webhook {
+	return &Webhook{URL: url, Secret: secret, Retries: 3, Backoff: time.Second}
+}
+
+// Put adds walked path to the batch, sent as {"path": path}.
+func (h *Webhook) Put(path string) error {
+	return h.Send(map[string]string{"path": path})
+}
+
+// Handle adds watch or diff event to the batch, errors are ignored; it can be passed to walks.Watch as the handler.
+func (h *Webhook) Handle(ev walks.Event) {
+	h.Send(ev)
+}
+
+// Send adds item to the batch, sending the batch if it is full. item is encoded with encoding/json.
+// The batch is sent without holding up concurrent Sends, which start the next batch meanwhile.
+func (h *Webhook) Send(item interface{}) error {
+	size := h.BatchSize
+	if size <= 0 {
+		size = 100
+	}
+	h.mu.Lock()
+	h.batch = append(h.batch, item)
+	var batch []interface{}
+	if len(h.batch) >= size {
+		batch = h.take()
+	}
+	h.mu.Unlock()
+	return h.send(batch)
+}
+
+// Flush sends the items batched so far.
+func (h *Webhook) Flush() error {
+	h.mu.Lock()
+	batch := h.take()
+	h.mu.Unlock()
+	return h.send(batch)
+}
+
+// Close sends the remaining batched items.
+func (h *Webhook) Close() error {
+	return h.Flush()
+}
+
+// take removes the batch from h and returns it; h.mu must be held.
+func (h *Webhook) take() []interface{} {
+	batch := h.batch
+	h.batch = nil
+	return batch
+}
+
+// send sends batch, retrying failed requests. The batch is dropped even if sending fails, so that one bad batch does not block the others.
+func (h *Webhook) send(batch []interface{}) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	backoff := h.Backoff
+	for try := 0; ; try++ {
+		err = h.post(body)
+		if err == nil || try >= h.Retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// post sends one request with body.
+func (h *Webhook) post(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(h.Secret) > 0 {
+		mac := hmac.New(sha256.New, h.Secret)
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("notify: webhook %v: %v", h.URL, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestWebhook(t *testing.T) {
+	secret := []byte("secret")
+	var mu sync.Mutex
+	var got [][]map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		if sig := r.Header.Get(SignatureHeader); sig != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("got signature %q", sig)
+		}
+		var batch []map[string]string
+		if err := json.Unmarshal(body, &batch); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		got = append(got, batch)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	h := NewWebhook(srv.URL, secret)
+	h.BatchSize = 2
+	for _, path := range []string{"a", "b", "c"} {
+		if err := h.Put(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]map[string]string{{{"path": "a"}, {"path": "b"}}, {{"path": "c"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got batches %v, want %v", got, want)
+	}
+}
+
+func TestWebhookStatus(t *testing.T) {
+	tries := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		tries++
+		http.Error(rw, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+	h := &Webhook{URL: srv.URL, Retries: 2}
+	h.Put("a")
+	if err := h.Flush(); err == nil {
+		t.Error("got nil error, want one")
+	}
+	if tries != 3 {
+		t.Errorf("got %v tries, want 3", tries)
+	}
+}
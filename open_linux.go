@@ -0,0 +1,16 @@
+package walks
+
+import (
+	"os"
+	"syscall"
+)
+
+// openRead opens file name for reading. With StrictReadOnly set, O_NOATIME is used where permitted (when the process owns the file or is privileged).
+func openRead(name string) (*os.File, error) {
+	if StrictReadOnly {
+		if f, err := os.OpenFile(name, os.O_RDONLY|syscall.O_NOATIME, 0); err == nil {
+			return f, nil
+		}
+	}
+	return os.Open(name)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+import "os"
+
+// openRead opens file name for reading.
+func openRead(name string) (*os.File, error) {
+	return os.Open(name)
+}
@@ -0,0 +1,120 @@
+package walks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// ErrOpenFilesUnsupported is returned by ScanOpenFiles on platforms without /proc/<pid>/fd (only Linux has it).
+var ErrOpenFilesUnsupported = errors.New("walks: open file detection is not supported on this platform")
+
+// fileKey identifies a file by device and inode.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// OpenFiles is an index of the files held open by running processes, as returned by ScanOpenFiles.
+// Processes of other users are only seen with enough privileges (eg root).
+type OpenFiles struct {
+	holders map[fileKey][]int
+	deleted map[fileKey]*DeletedOpenFile
+}
+
+// Holders returns the IDs of the processes holding open the file described by info (sorted), or nil if none does.
+func (o *OpenFiles) Holders(info os.FileInfo) []int {
+	dev, ino, ok := fileID(info)
+	if !ok {
+		return nil
+	}
+	return o.holders[fileKey{dev, ino}]
+}
+
+// add records that process pid holds file of info open.
+func (o *OpenFiles) add(info os.FileInfo, pid int) {
+	dev, ino, ok := fileID(info)
+	if !ok {
+		return
+	}
+	key := fileKey{dev, ino}
+	pids := o.holders[key]
+	if len(pids) > 0 && pids[len(pids)-1] == pid {
+		return
+	}
+	o.holders[key] = append(pids, pid)
+}
+
+// OpenFile is a walked file held open by running processes.
+type OpenFile struct {
+	Path string
+	PIDs []int
+}
+
+// HeldOpen walks recursively given directory structure and reports the regular files currently held open by running processes,
+// eg to check that "unused" files really are unused before deleting them. It is only supported on Linux.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before HeldOpen call.
+func HeldOpen(root string) ([]OpenFile, error) {
+	open, err := ScanOpenFiles()
+	if err != nil {
+		return nil, err
+	}
+	var held []OpenFile
+	it := NewIterator(root)
+	for e := it.Next(); e != nil; e = it.Next() {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if pids := open.Holders(info); pids != nil {
+			held = append(held, OpenFile{Path: e.Path, PIDs: pids})
+		}
+	}
+	sort.Slice(held, func(i, j int) bool { return held[i].Path < held[j].Path })
+	return held, it.Err()
+}
+
+// DeletedOpenFile is a file removed from the filesystem (unlinked), that still takes space, because processes hold it open.
+// Such files explain why df reports more used space than du finds.
+type DeletedOpenFile struct {
+	// Path is the path the file had when it was removed.
+	Path string
+	Size int64
+	PIDs []int
+}
+
+// Deleted returns the deleted files held open, that were at or under path (all of them for ""), largest first.
+func (o *OpenFiles) Deleted(path string) []DeletedOpenFile {
+	var files []DeletedOpenFile
+	dir := filepath.Clean(path)
+	for _, f := range o.deleted {
+		if _, ok := depthUnder(dir, f.Path); ok || path == "" {
+			files = append(files, *f)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	return files
+}
+
+// DeletedUsage returns the space taken by deleted files held open, that were at or under path, with the files themselves (see OpenFiles.Deleted).
+// Added to usage reported by walks (eg Snapshot.Usage), it accounts for the difference from df. It is only supported on Linux.
+func DeletedUsage(path string) (int64, []DeletedOpenFile, error) {
+	open, err := ScanOpenFiles()
+	if err != nil {
+		return 0, nil, err
+	}
+	files := open.Deleted(path)
+	var total int64
+	for _, f := range files {
+		total += f.Size
+	}
+	return total, files, nil
+}
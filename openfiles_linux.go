@@ -0,0 +1,52 @@
+package walks
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ScanOpenFiles reads /proc/<pid>/fd of all running processes and returns the index of the files they hold open.
+// Processes exiting or closing files during the scan are passed over.
+func ScanOpenFiles() (*OpenFiles, error) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	o := &OpenFiles{holders: make(map[fileKey][]int), deleted: make(map[fileKey]*DeletedOpenFile)}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := "/proc/" + proc.Name() + "/fd"
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			// Stat of the fd link describes the open file itself, even if it was deleted since.
+			info, err := os.Stat(fdDir + "/" + fd.Name())
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			o.add(info, pid)
+			if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Nlink == 0 {
+				key := fileKey{uint64(st.Dev), uint64(st.Ino)}
+				if o.deleted[key] == nil {
+					link, _ := os.Readlink(fdDir + "/" + fd.Name())
+					o.deleted[key] = &DeletedOpenFile{Path: strings.TrimSuffix(link, " (deleted)"), Size: info.Size()}
+				}
+			}
+		}
+	}
+	for key, pids := range o.holders {
+		sort.Ints(pids)
+		if f := o.deleted[key]; f != nil {
+			f.PIDs = pids
+		}
+	}
+	return o, nil
+}
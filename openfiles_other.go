@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+// ScanOpenFiles returns ErrOpenFilesUnsupported, open files cannot be listed on this platform.
+func ScanOpenFiles() (*OpenFiles, error) {
+	return nil, ErrOpenFilesUnsupported
+}
@@ -0,0 +1,194 @@
+package walks
+
+import (
+	"errors"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// ErrOpTimeout is the error of a filesystem operation, that did not complete within Walker's OpTimeout.
+// It is returned wrapped in *fs.PathError naming the operation, and handled like other errors of the walk (see Errors).
+var ErrOpTimeout = errors.New("walks: filesystem operation timed out")
+
+// opWorkers is the number of goroutines of an opPool.
+const opWorkers = 16
+
+// maxHungOps is the number of hung operations an opPool replaces its workers for;
+// with more of them, the pool shrinks, and operations waiting for a worker time out without being started.
+const maxHungOps = 64
+
+// HungPath is a filesystem operation, that did not complete within Walker's OpTimeout.
+type HungPath struct {
+	// Op is the operation: "open", "stat", "lstat" or "readdir".
+	Op string
+	// Path is the path the operation is on.
+	Path string
+	// Started is the time the operation was started at.
+	Started time.Time
+}
+
+// opPool is a small pool of goroutines running filesystem operations of walks of a Walker, so that their callers can stop waiting for them.
+// An operation hanging in the kernel cannot be cancelled: its worker is replaced and exits once the operation returns.
+type opPool struct {
+	w     *Walker
+	tasks chan *opTask
+	quit  chan struct{}
+}
+
+// opTask is an operation run by an opPool.
+type opTask struct {
+	HungPath
+	fn   func() (interface{}, error)
+	undo func(interface{}) // releases the result of an operation completing after its timeout, if not nil
+	done chan opResult
+}
+
+// opResult is the result of an opTask.
+type opResult struct {
+	v   interface{}
+	err error
+}
+
+// newOpPool returns a running opPool of Walker w.
+func newOpPool(w *Walker) *opPool {
+	p := &opPool{w: w, tasks: make(chan *opTask), quit: make(chan struct{})}
+	for i := 0; i < opWorkers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// close stops the idle workers of the pool; workers of hung operations exit once the operations return.
+func (p *opPool) close() {
+	close(p.quit)
+}
+
+// work runs tasks until the pool is closed, or until a task, that hung and whose worker was replaced, returns.
+func (p *opPool) work() {
+	for {
+		select {
+		case <-p.quit:
+			return
+		case t := <-p.tasks:
+			v, err := t.fn()
+			t.done <- opResult{v, err}
+			p.w.hungMu.Lock()
+			replaced, hung := p.w.hung[t]
+			delete(p.w.hung, t)
+			p.w.hungMu.Unlock()
+			if hung {
+				if t.undo != nil && err == nil {
+					t.undo(v)
+				}
+				if replaced {
+					return
+				}
+			}
+		}
+	}
+}
+
+// run runs operation op of path (fn) on the pool, waiting up to timeout for it to complete.
+func (p *opPool) run(op, path string, timeout time.Duration, fn func() (interface{}, error), undo func(interface{})) (interface{}, error) {
+	t := &opTask{HungPath: HungPath{Op: op, Path: path, Started: time.Now()}, fn: fn, undo: undo, done: make(chan opResult, 1)}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case p.tasks <- t:
+	case <-p.quit:
+		return fn()
+	case <-timer.C:
+		return nil, &fs.PathError{Op: op, Path: path, Err: ErrOpTimeout}
+	}
+	select {
+	case r := <-t.done:
+		return r.v, r.err
+	case <-timer.C:
+	}
+	p.w.hungMu.Lock()
+	select {
+	case r := <-t.done:
+		// completed just now
+		p.w.hungMu.Unlock()
+		return r.v, r.err
+	default:
+	}
+	if p.w.hung == nil {
+		p.w.hung = make(map[*opTask]bool)
+	}
+	replace := len(p.w.hung) < maxHungOps
+	p.w.hung[t] = replace
+	p.w.hungMu.Unlock()
+	if replace {
+		go p.work()
+	}
+	if p.w.Hung != nil {
+		p.w.Hung(t.HungPath)
+	}
+	return nil, &fs.PathError{Op: op, Path: path, Err: ErrOpTimeout}
+}
+
+// timed runs filesystem operation op of path (fn), failing it with ErrOpTimeout, if it does not complete within Walker's OpTimeout.
+// undo, if not nil, releases the result of the operation, when it completes after the timeout (eg closes an opened directory).
+func (w *Walker) timed(op, path string, fn func() (interface{}, error), undo func(interface{})) (interface{}, error) {
+	if w.OpTimeout <= 0 {
+		return fn()
+	}
+	w.lifeMu.Lock()
+	p := w.ops
+	w.lifeMu.Unlock()
+	if p == nil {
+		return fn()
+	}
+	return p.run(op, path, w.OpTimeout, fn, undo)
+}
+
+// stat returns the info of path following symbolic links, like Walker's FS does, within Walker's OpTimeout.
+func (w *Walker) stat(path string) (fs.FileInfo, error) {
+	v, err := w.timed("stat", path, func() (interface{}, error) {
+		return w.fsys().Stat(path)
+	}, nil)
+	info, _ := v.(fs.FileInfo)
+	return info, err
+}
+
+// timedEntry is an entry, whose info is read within Walker's OpTimeout.
+type timedEntry struct {
+	fs.DirEntry
+	w    *Walker
+	path string
+}
+
+// Info returns the info of the entry.
+func (e timedEntry) Info() (fs.FileInfo, error) {
+	v, err := e.w.timed("lstat", e.path, func() (interface{}, error) {
+		return e.DirEntry.Info()
+	}, nil)
+	info, _ := v.(fs.FileInfo)
+	return info, err
+}
+
+// timedEntries wraps entries of directory dir to read their info within Walker's OpTimeout, when it is set.
+func (w *Walker) timedEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	if w.OpTimeout <= 0 {
+		return entries
+	}
+	for i, entry := range entries {
+		entries[i] = timedEntry{DirEntry: entry, w: w, path: joinPath(dir, entry.Name())}
+	}
+	return entries
+}
+
+// HungPaths returns the filesystem operations of w's walks, that timed out (see OpTimeout) and have still not returned,
+// oldest first, eg to tell which mounts a walk got stuck on.
+func (w *Walker) HungPaths() []HungPath {
+	w.hungMu.Lock()
+	defer w.hungMu.Unlock()
+	hung := make([]HungPath, 0, len(w.hung))
+	for t := range w.hung {
+		hung = append(hung, t.HungPath)
+	}
+	sort.Slice(hung, func(i, j int) bool { return hung[i].Started.Before(hung[j].Started) })
+	return hung
+}
@@ -0,0 +1,290 @@
+package walks
+
+import (
+	"io/fs"
+	"regexp"
+	"time"
+)
+
+// Option configures a Walker created with New, so that configuration can grow without changing signatures.
+// Options are applied in order; an option failing (eg WithIgnoreFile of an unreadable file) makes the Walker's walks return its error.
+type Option func(w *Walker) error
+
+// WithDepth sets Walker's Depth, -1 means no limit.
+func WithDepth(depth int) Option {
+	return func(w *Walker) error {
+		w.Depth = depth
+		return nil
+	}
+}
+
+// WithIgnore sets Walker's Ignore.
+func WithIgnore(ignore *regexp.Regexp) Option {
+	return func(w *Walker) error {
+		w.Ignore = ignore
+		return nil
+	}
+}
+
+// WithIgnoreFile sets Walker's Ignore from ignore file, like Walker's SetIgnore.
+func WithIgnoreFile(path string) Option {
+	return func(w *Walker) error {
+		return w.SetIgnore(path)
+	}
+}
+
+// WithGitignoreFile sets Walker's IgnoreRules from ignore file in gitignore format.
+func WithGitignoreFile(path string) Option {
+	return func(w *Walker) error {
+		rules, err := readGitignore(path)
+		if err != nil {
+			return err
+		}
+		w.IgnoreRules = rules
+		return nil
+	}
+}
+
+// WithIgnoreFiles sets Walker's IgnoreFiles, the names of ignore files applied as the walk descends.
+func WithIgnoreFiles(names ...string) Option {
+	return func(w *Walker) error {
+		w.IgnoreFiles = names
+		return nil
+	}
+}
+
+// WithSearch sets Walker's Search.
+func WithSearch(search *regexp.Regexp) Option {
+	return func(w *Walker) error {
+		w.Search = search
+		return nil
+	}
+}
+
+// WithInclude sets Walker's Search to regexp pattern, so that only matching directories and files are passed to the actions.
+func WithInclude(pattern string) Option {
+	return func(w *Walker) error {
+		search, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		w.Search = search
+		return nil
+	}
+}
+
+// WithFilter sets Walker's Filter.
+func WithFilter(filter func(path string, d fs.DirEntry) bool) Option {
+	return func(w *Walker) error {
+		w.Filter = filter
+		return nil
+	}
+}
+
+// WithMaxWorkers sets Walker's MaxWorkers.
+func WithMaxWorkers(n int) Option {
+	return func(w *Walker) error {
+		w.MaxWorkers = n
+		return nil
+	}
+}
+
+// WithOrdered sets Walker's Ordered.
+func WithOrdered(ordered bool) Option {
+	return func(w *Walker) error {
+		w.Ordered = ordered
+		return nil
+	}
+}
+
+// WithSymlinks sets Walker's Symlinks and SymlinkAction.
+func WithSymlinks(policy SymlinkPolicy, action func(Entry) error) Option {
+	return func(w *Walker) error {
+		w.Symlinks, w.SymlinkAction = policy, action
+		return nil
+	}
+}
+
+// WithOtherAction sets Walker's OtherAction, called for devices, named pipes and sockets.
+func WithOtherAction(action func(Entry) error) Option {
+	return func(w *Walker) error {
+		w.OtherAction = action
+		return nil
+	}
+}
+
+// WithErrors sets Walker's Errors policy and ErrorAction (which may be nil).
+func WithErrors(policy ErrorPolicy, action func(path string, err error) error) Option {
+	return func(w *Walker) error {
+		w.Errors, w.ErrorAction = policy, action
+		return nil
+	}
+}
+
+// WithRoot sets Walker's Root.
+func WithRoot(policy RootPolicy) Option {
+	return func(w *Walker) error {
+		w.Root = policy
+		return nil
+	}
+}
+
+// WithSnapshots sets Walker's Snapshots.
+func WithSnapshots(policy SnapshotPolicy) Option {
+	return func(w *Walker) error {
+		w.Snapshots = policy
+		return nil
+	}
+}
+
+// WithDirSummary sets Walker's DirSummary.
+func WithDirSummary(summary func(DirStats)) Option {
+	return func(w *Walker) error {
+		w.DirSummary = summary
+		return nil
+	}
+}
+
+// WithDirActionPost sets Walker's DirActionPost.
+func WithDirActionPost(action func(Entry) error) Option {
+	return func(w *Walker) error {
+		w.DirActionPost = action
+		return nil
+	}
+}
+
+// WithGuard sets Walker's DepthCeiling and Guard.
+func WithGuard(ceiling int, guard func(path string, err error)) Option {
+	return func(w *Walker) error {
+		w.DepthCeiling, w.Guard = ceiling, guard
+		return nil
+	}
+}
+
+// WithVanished sets Walker's Vanished.
+func WithVanished(vanished func(path string)) Option {
+	return func(w *Walker) error {
+		w.Vanished = vanished
+		return nil
+	}
+}
+
+// WithDirFD sets Walker's DirFD.
+func WithDirFD(dirFD bool) Option {
+	return func(w *Walker) error {
+		w.DirFD = dirFD
+		return nil
+	}
+}
+
+// WithCapabilities sets Walker's Capabilities.
+func WithCapabilities(c Capability) Option {
+	return func(w *Walker) error {
+		w.Capabilities = c
+		return nil
+	}
+}
+
+// WithQuota sets Walker's Quota.
+func WithQuota(q Quota) Option {
+	return func(w *Walker) error {
+		w.Quota = q
+		return nil
+	}
+}
+
+// WithNested sets Walker's Nested.
+func WithNested(policy NestedPolicy) Option {
+	return func(w *Walker) error {
+		w.Nested = policy
+		return nil
+	}
+}
+
+// WithFS sets Walker's FS.
+func WithFS(fsys FS) Option {
+	return func(w *Walker) error {
+		w.FS = fsys
+		return nil
+	}
+}
+
+// WithFastStat sets Walker's FastStat.
+func WithFastStat(fields StatFields) Option {
+	return func(w *Walker) error {
+		w.FastStat = fields
+		return nil
+	}
+}
+
+// WithLongPaths sets Walker's LongPaths.
+func WithLongPaths(long bool) Option {
+	return func(w *Walker) error {
+		w.LongPaths = long
+		return nil
+	}
+}
+
+// WithMounts sets Walker's Mounts policy and MountTimeout.
+func WithMounts(policy MountPolicy, timeout time.Duration) Option {
+	return func(w *Walker) error {
+		w.Mounts, w.MountTimeout = policy, timeout
+		return nil
+	}
+}
+
+// WithCache sets Walker's Cache.
+func WithCache(c *DirCache) Option {
+	return func(w *Walker) error {
+		w.Cache = c
+		return nil
+	}
+}
+
+// WithTempQuota sets Walker's TempQuota.
+func WithTempQuota(bytes int64) Option {
+	return func(w *Walker) error {
+		w.TempQuota = bytes
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware to Walker's chain, like Use.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(w *Walker) error {
+		w.Use(middleware...)
+		return nil
+	}
+}
+
+// WithOpTimeout sets Walker's OpTimeout and Hung (which may be nil).
+func WithOpTimeout(timeout time.Duration, hung func(HungPath)) Option {
+	return func(w *Walker) error {
+		w.OpTimeout, w.Hung = timeout, hung
+		return nil
+	}
+}
+
+// WithCountBytes sets Walker's CountBytes.
+func WithCountBytes(count bool) Option {
+	return func(w *Walker) error {
+		w.CountBytes = count
+		return nil
+	}
+}
+
+// WithRetainedEntries sets Walker's RetainEntries.
+func WithRetainedEntries(retain bool) Option {
+	return func(w *Walker) error {
+		w.RetainEntries = retain
+		return nil
+	}
+}
+
+// WithClock sets Walker's Clock.
+func WithClock(clock Clock) Option {
+	return func(w *Walker) error {
+		w.Clock = clock
+		return nil
+	}
+}
@@ -0,0 +1,280 @@
+package walks
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"runtime"
+	"sync"
+)
+
+// orderedItem is an entry waiting for its prepared value, to be emitted in turn.
+type orderedItem struct {
+	entry Entry
+	value interface{}
+	err   error
+	done  chan struct{}
+}
+
+// WalkOrdered walks recursively given directory structure and calls emit with every entry strictly sequentially,
+// in depth-first order with each directory's entries sorted by name (as Iterator does), so emit is a single writer producing
+// deterministic output, eg a tar stream or a manifest.
+// The per-entry work, eg collecting metadata or hashing, is done upstream by prepare, called with up to workers entries concurrently
+// (0 means runtime.NumCPU()); its result is passed to emit with the entry. nil prepare passes nil values.
+// At most a few entries per worker are prepared ahead of emit, so memory use does not grow with the tree.
+// First error returned by prepare or emit, or listing error, stops the walk and is returned.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before WalkOrdered call.
+func WalkOrdered(ctx context.Context, root string, workers int, prepare func(Entry) (interface{}, error), emit func(Entry, interface{}) error) error {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobs := make(chan *orderedItem)
+	pending := make(chan *orderedItem, 4*workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range jobs {
+				if prepare != nil && ctx.Err() == nil {
+					item.value, item.err = prepare(item.entry)
+				}
+				close(item.done)
+			}
+		}()
+	}
+	var listErr error
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		it := NewIterator(root)
+		it.RetainEntries = true
+		for e := it.Next(); e != nil; e = it.Next() {
+			item := &orderedItem{entry: *e, done: make(chan struct{})}
+			// pending is added to first, so that it bounds the number of entries in flight.
+			select {
+			case pending <- item:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- item:
+			case <-ctx.Done():
+				close(item.done)
+				return
+			}
+		}
+		listErr = it.Err()
+	}()
+	var firstErr error
+	for item := range pending {
+		<-item.done
+		if firstErr != nil {
+			continue
+		}
+		if firstErr = item.err; firstErr == nil {
+			firstErr = emit(item.entry, item.value)
+		}
+		if firstErr != nil {
+			cancel()
+		}
+	}
+	wg.Wait()
+	if firstErr == nil {
+		firstErr = listErr
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return firstErr
+}
+
+// listing is a directory listing read ahead of an ordered walk.
+type listing struct {
+	infos []fs.DirEntry
+	err   error
+	ready chan struct{}
+}
+
+// orderedDir is a directory being delivered by an ordered walk.
+type orderedDir struct {
+	node  *dirNode
+	level int // level of the directory's entries
+	infos []fs.DirEntry
+	next  int
+	scan  int                 // index of the next entry to consider for reading ahead
+	ahead map[string]*listing // listings of the next subdirectories being read ahead
+}
+
+// walkOrdered is walk in Walker's Ordered mode: directory listings are read ahead concurrently,
+// while the actions are called from one goroutine in depth-first order, with each directory's entries sorted by name.
+// Listings of up to as many next subdirectories of each directory on the way as there are workers are read ahead,
+// so that memory use does not grow with the width of the tree.
+func (w *Walker) walkOrdered(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error, counts *walkCounts) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	workers := w.MaxWorkers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	sem := make(chan struct{}, workers)
+	readAhead := func(dir string) *listing {
+		l := &listing{ready: make(chan struct{})}
+		go func() {
+			defer close(l.ready)
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				l.err = ctx.Err()
+				return
+			}
+			defer func() { <-sem }()
+			if ctx.Err() != nil {
+				l.err = ctx.Err()
+				return
+			}
+			if l.infos, l.err = w.readDir(dir); l.err == nil {
+				l.infos = w.timedEntries(dir, w.fastEntries(dir, l.infos))
+			}
+		}()
+		return l
+	}
+	// fill starts reading ahead the listings of the next subdirectories of d, up to workers of them.
+	fill := func(d *orderedDir) {
+		if w.Depth != -1 && d.level >= w.Depth {
+			return
+		}
+		for len(d.ahead) < workers && d.scan < len(d.infos) {
+			info := d.infos[d.scan]
+			d.scan++
+			pathName := joinPath(d.node.stats.Path, info.Name())
+			if info.IsDir() && !w.ignored(pathName, true) && !d.node.ignore.match(pathName, true) && !d.node.mounts.has(pathName) {
+				d.ahead[info.Name()] = readAhead(pathName)
+			}
+		}
+	}
+	var stack []*orderedDir
+	sink := errorSink{counts: counts}
+	// sizes of files are only read, when directory stats are reported
+	sizes := w.CountBytes || w.DirSummary != nil || w.bus.wants(EventDirDone)
+	report := func(n *dirNode, stats DirStats) {
+		w.bus.publish(WalkEvent{Kind: EventDirDone, Path: stats.Path, Stats: &stats})
+		if w.DirSummary != nil {
+			w.DirSummary(stats)
+		}
+	}
+	// push starts delivering directory node, whose entries are at given level, and reads its first subdirectories ahead.
+	push := func(node *dirNode, level int, l *listing) error {
+		<-l.ready
+		dir := node.stats.Path
+		err := l.err
+		if os.IsNotExist(err) && node.parent != nil {
+			w.vanished(dir)
+			node.done(report)
+			return nil
+		}
+		if err == nil && len(w.IgnoreFiles) > 0 {
+			node.ignore, err = w.loadIgnoreFiles(dir, l.infos, node.ignore)
+		}
+		if err != nil {
+			if err = w.pathError(&sink, dir, err); err == nil {
+				node.done(report)
+			}
+			return err
+		}
+		counts.depth(level)
+		d := &orderedDir{node: node, level: level, infos: l.infos, ahead: make(map[string]*listing)}
+		fill(d)
+		stack = append(stack, d)
+		return nil
+	}
+	// pop finishes delivering the top directory.
+	pop := func() error {
+		d := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		d.node.done(report)
+		if w.DirActionPost == nil || d.node.parent == nil {
+			return nil
+		}
+		if e := (Entry{Path: d.node.stats.Path, Depth: d.node.stats.Depth, info: d.node.info}); w.acts(d.node.inSnapshot) && w.included(e) {
+			if err := w.wrap(w.DirActionPost)(e); err != nil && err != SkipDir {
+				return err
+			}
+		}
+		return nil
+	}
+	rootNode := newDirNode(nil, root, -1)
+	if info, err := w.stat(root); err == nil {
+		rootNode.setID(info)
+	}
+	rootNode.mounts = w.newMountTable(root)
+	err := push(rootNode, 0, readAhead(root))
+	for err == nil && len(stack) > 0 {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		d := stack[len(stack)-1]
+		if d.next == len(d.infos) {
+			err = pop()
+			continue
+		}
+		info := d.infos[d.next]
+		d.next++
+		l := d.ahead[info.Name()]
+		delete(d.ahead, info.Name())
+		fill(d)
+		var c classified
+		if c, err = w.classify(ctx, nil, &sink, d.node, d.level, info, sizes); err != nil {
+			continue
+		}
+		switch c.class {
+		case classSkipRest:
+			err = pop()
+		case classDir:
+			if c.acts {
+				if aerr := dirAction(c.entry); aerr == SkipDir {
+					continue
+				} else if aerr != nil {
+					err = aerr
+					continue
+				}
+			}
+			if w.Depth != -1 && d.level >= w.Depth {
+				continue
+			}
+			child := newDirNode(d.node, c.entry.Path, d.level)
+			child.setID(c.entry.info)
+			w.boundary(d.node, child)
+			child.info, child.inSnapshot = c.entry.info, c.inSnapshot
+			if !w.guard(child, d.level+1) {
+				continue
+			}
+			if l == nil || c.followed {
+				l = readAhead(c.entry.Path)
+			}
+			d.node.spawn()
+			err = push(child, d.level+1, l)
+		case classFile, classOther:
+			action := fileAction
+			if c.class == classOther {
+				action = w.wrap(w.OtherAction)
+			}
+			if c.acts {
+				if aerr := action(c.entry); aerr == SkipDir {
+					err = pop()
+				} else if aerr != nil {
+					err = aerr
+				}
+			}
+		}
+	}
+	if err == SkipAll {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return sink.err()
+}
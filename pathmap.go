@@ -0,0 +1,137 @@
+package walks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// PathMapper maps a source path, relative to the walked root, to a destination path, relative to the destination root.
+// It is consulted by sinks transferring files (CopyTree, MirrorDirs), so paths can be flattened, re-rooted or renamed during transfer.
+type PathMapper func(src string) string
+
+// Flatten is a PathMapper that drops all directories, placing every file directly under destination root.
+func Flatten(src string) string {
+	return filepath.Base(src)
+}
+
+// Reroot returns a PathMapper that replaces leading directory from with to.
+// Paths not under from are left unchanged.
+// Empty from matches every path, so Reroot("", prefix) moves everything under prefix.
+func Reroot(from string, to string) PathMapper {
+	from = filepath.Clean(from)
+	return func(src string) string {
+		if from == "." {
+			return filepath.Join(to, src)
+		}
+		if src == from {
+			return filepath.Clean(to)
+		}
+		if strings.HasPrefix(src, from+string(filepath.Separator)) {
+			return filepath.Join(to, src[len(from)+1:])
+		}
+		return src
+	}
+}
+
+// Rename returns a PathMapper that passes the base name of the path through fn, keeping the directories intact.
+func Rename(fn func(name string) string) PathMapper {
+	return func(src string) string {
+		return filepath.Join(filepath.Dir(src), fn(filepath.Base(src)))
+	}
+}
+
+// ChainMappers returns a PathMapper that applies given mappers in order.
+func ChainMappers(mappers ...PathMapper) PathMapper {
+	return func(src string) string {
+		for _, mapper := range mappers {
+			src = mapper(src)
+		}
+		return src
+	}
+}
+
+// CopyTree copies recursively files under src to dst, keeping file permissions.
+// If mapper is not nil, each file path relative to src is passed through it and the result is used as the path relative to dst.
+// Directories are created as needed for the copied files, so empty directories are not copied (see MirrorDirs).
+// Space needed for all files is checked before copying anything (see CheckSpace), so that the copy does not fail halfway with a full destination.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before CopyTree call.
+func CopyTree(src string, dst string, mapper PathMapper) error {
+	need, err := TreeSize(src)
+	if err != nil {
+		return err
+	}
+	if err := CheckSpace(dst, need); err != nil {
+		return err
+	}
+	var firstErr error
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			firstErr = err
+			return
+		}
+		if mapper != nil {
+			rel = mapper(rel)
+		}
+		firstErr = copyFile(path, filepath.Join(dst, rel))
+	}
+	if _, err := WalkLinearE(src, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// CopyTree copies files under src to dst like package's CopyTree does, if w grants CanRead and CanWrite (see Require).
+func (w *Walker) CopyTree(src string, dst string, mapper PathMapper) error {
+	if err := w.Require(CanRead|CanWrite, "copy", dst); err != nil {
+		return err
+	}
+	return CopyTree(src, dst, mapper)
+}
+
+// cloneSeq makes names of temporary clones unique.
+var cloneSeq uint64
+
+// copyFile copies contents and permissions of file src to dst, creating missing parent directories of dst.
+// When the filesystem supports it (FICLONE on Btrfs/XFS, clonefile on APFS), dst is created as a copy-on-write clone of src,
+// which is fast and takes no extra space; otherwise contents are copied.
+// Free space on the destination is checked before each copy (see CheckSpace).
+func copyFile(src string, dst string) error {
+	if err := writable("copy", dst); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	if err := CheckSpace(dst, info.Size()); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(dst), fmt.Sprintf(".%v.clone-%v-%v", filepath.Base(dst), os.Getpid(), atomic.AddUint64(&cloneSeq, 1)))
+	if err := clone(in, tmp); err == nil {
+		if err := os.Chmod(tmp, info.Mode().Perm()); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return os.Rename(tmp, dst)
+	}
+	return WriteFileAtomic(dst, info.Mode().Perm(), func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
@@ -0,0 +1,40 @@
+package walks
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Paths of walked entries are built by joining the directory and the entry's name with the platform's separator (like filepath.Join would),
+// but without cleaning the walked root: entries under root "./src" are "./src/a" (`.\src\a` on Windows), so they keep starting with the root as given.
+// Ignore and Search are matched against paths in slash form (see filepath.ToSlash) on every platform, so that the same ignore files work on Windows.
+
+// joinPath returns the path of entry name in directory dir.
+// Roots ending with a separator ("/", `C:\`) and drive-relative roots ("C:") are joined without adding one.
+func joinPath(dir string, name string) string {
+	if dir == "" {
+		return name
+	}
+	if os.IsPathSeparator(dir[len(dir)-1]) || len(dir) == 2 && dir[1] == ':' && filepath.VolumeName(dir) == dir {
+		return dir + name
+	}
+	return dir + string(filepath.Separator) + name
+}
+
+// matchPath reports whether path in slash form matches re.
+func matchPath(re *regexp.Regexp, path string) bool {
+	return re.MatchString(filepath.ToSlash(path))
+}
+
+// LongPath returns the absolute form of path, that is not limited to MAX_PATH (260) characters on Windows:
+// `C:\dir` becomes `\\?\C:\dir` and `\\server\share\dir` becomes `\\?\UNC\server\share\dir`.
+// Such paths are taken literally by Windows, so they must not contain "." or ".." elements, neither slashes, which LongPath takes care of.
+// On other platforms the absolute path is returned as it is.
+func LongPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return longPath(abs), nil
+}
@@ -0,0 +1,232 @@
+package walks
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PIIDetector detects one kind of personal or regulated data in text.
+type PIIDetector struct {
+	Name    string
+	Pattern *regexp.Regexp
+	// Validate, if not nil, rejects matches of Pattern, that are not valid data (eg failing a checksum).
+	Validate func(match string) bool
+	// Confidence is the probability (0 to 1), that a single valid match is real data; each further match raises the confidence of a finding.
+	Confidence float64
+}
+
+// DefaultPIIDetectors are the detectors used by ScanPII when none are given:
+// email addresses, payment card numbers (validated with Luhn), IBANs (validated with mod 97), US social security numbers
+// and Estonian personal identification codes (validated with their check digit).
+var DefaultPIIDetectors = []PIIDetector{
+	{Name: "email", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`), Confidence: 0.9},
+	{Name: "card", Pattern: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), Validate: luhn, Confidence: 0.7},
+	{Name: "iban", Pattern: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`), Validate: ibanValid, Confidence: 0.8},
+	{Name: "us-ssn", Pattern: regexp.MustCompile(`\b(?:00[1-9]|0[1-9]\d|[1-8]\d\d)-(?:0[1-9]|[1-9]\d)-(?:000[1-9]|00[1-9]\d|0[1-9]\d\d|[1-9]\d{3})\b`), Confidence: 0.4},
+	{Name: "ee-isikukood", Pattern: regexp.MustCompile(`\b[1-6]\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{4}\b`), Validate: isikukoodValid, Confidence: 0.6},
+}
+
+// PIIFinding reports the matches of one detector in one file.
+type PIIFinding struct {
+	Path     string
+	Detector string
+	Matches  int
+	// Samples are up to 3 of the matches, redacted to their last 4 characters, for reviewing findings without spreading the data.
+	Samples []string
+	// Confidence is the probability (0 to 1), that the file really contains such data, growing with the number of matches.
+	Confidence float64
+}
+
+// PIIOptions configure ScanPII.
+type PIIOptions struct {
+	// Detectors are applied to each file; nil means DefaultPIIDetectors.
+	Detectors []PIIDetector
+	// Workers is the number of files scanned in parallel; 0 means 1.
+	Workers int
+	// SampleSize is the number of leading bytes of each file scanned; 0 means 1 MiB.
+	SampleSize int64
+	// MinConfidence leaves out findings with lower confidence.
+	MinConfidence float64
+}
+
+// ScanPII walks recursively given directory structure and samples the leading bytes of every text file (files with NUL bytes are passed over),
+// applying detectors of personal and regulated data, for compliance scans. Findings are returned sorted by path and detector.
+// Files that cannot be read are returned in failed with their errors, instead of stopping the scan.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before ScanPII call.
+func ScanPII(root string, opts PIIOptions) (findings []PIIFinding, failed map[string]error, err error) {
+	detectors, workers, sampleSize := opts.Detectors, opts.Workers, opts.SampleSize
+	if detectors == nil {
+		detectors = DefaultPIIDetectors
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+	if sampleSize <= 0 {
+		sampleSize = 1 << 20
+	}
+	failed = make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, sampleSize)
+			for path := range queue {
+				found, err := piiScan(detectors, path, buf)
+				mu.Lock()
+				if err != nil {
+					failed[path] = err
+				}
+				for _, f := range found {
+					if f.Confidence >= opts.MinConfidence {
+						findings = append(findings, f)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	it := NewIterator(root)
+	for e := it.Next(); e != nil; e = it.Next() {
+		if e.Type().IsRegular() {
+			queue <- e.Path
+		}
+	}
+	close(queue)
+	wg.Wait()
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Path != findings[j].Path {
+			return findings[i].Path < findings[j].Path
+		}
+		return findings[i].Detector < findings[j].Detector
+	})
+	return findings, failed, it.Err()
+}
+
+// ScanPII scans files under root like package's ScanPII does, if w grants CanRead (see Require).
+func (w *Walker) ScanPII(root string, opts PIIOptions) (findings []PIIFinding, failed map[string]error, err error) {
+	if err := w.Require(CanRead, "pii", root); err != nil {
+		return nil, nil, err
+	}
+	return ScanPII(root, opts)
+}
+
+// piiScan applies detectors to the leading bytes of text file path, read into buf.
+func piiScan(detectors []PIIDetector, path string, buf []byte) ([]PIIFinding, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	sample := buf[:n]
+	if bytes.IndexByte(sample, 0) >= 0 {
+		return nil, nil
+	}
+	var found []PIIFinding
+	for _, d := range detectors {
+		finding := PIIFinding{Path: path, Detector: d.Name}
+		for _, m := range d.Pattern.FindAllString(string(sample), -1) {
+			if d.Validate != nil && !d.Validate(m) {
+				continue
+			}
+			finding.Matches++
+			if len(finding.Samples) < 3 {
+				finding.Samples = append(finding.Samples, redact(m))
+			}
+		}
+		if finding.Matches > 0 {
+			finding.Confidence = 1 - math.Pow(1-d.Confidence, float64(finding.Matches))
+			found = append(found, finding)
+		}
+	}
+	return found, nil
+}
+
+// redact masks all but the last 4 characters of s.
+func redact(s string) string {
+	r := []rune(s)
+	if len(r) <= 4 {
+		return strings.Repeat("*", len(r))
+	}
+	return strings.Repeat("*", len(r)-4) + string(r[len(r)-4:])
+}
+
+// digits returns the decimal digits of s, leaving out everything else.
+func digits(s string) []int {
+	var ds []int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			ds = append(ds, int(c-'0'))
+		}
+	}
+	return ds
+}
+
+// luhn reports whether the digits of s pass the Luhn checksum of payment card numbers.
+func luhn(s string) bool {
+	ds := digits(s)
+	if len(ds) < 13 || len(ds) > 19 {
+		return false
+	}
+	sum := 0
+	for i := range ds {
+		d := ds[len(ds)-1-i]
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// ibanValid reports whether s is an IBAN with a valid mod 97 checksum.
+func ibanValid(s string) bool {
+	s = strings.Replace(s, " ", "", -1)
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	rearranged := s[4:] + s[:4]
+	rem := 0
+	for _, c := range rearranged {
+		switch {
+		case c >= '0' && c <= '9':
+			rem = (rem*10 + int(c-'0')) % 97
+		case c >= 'A' && c <= 'Z':
+			rem = (rem*100 + int(c-'A') + 10) % 97
+		default:
+			return false
+		}
+	}
+	return rem == 1
+}
+
+// isikukoodValid reports whether s is an Estonian personal identification code with a valid check digit.
+func isikukoodValid(s string) bool {
+	ds := digits(s)
+	if len(ds) != 11 {
+		return false
+	}
+	for _, weights := range [][]int{{1, 2, 3, 4, 5, 6, 7, 8, 9, 1}, {3, 4, 5, 6, 7, 8, 9, 1, 2, 3}} {
+		sum := 0
+		for i, w := range weights {
+			sum += ds[i] * w
+		}
+		if check := sum % 11; check != 10 {
+			return check == ds[10]
+		}
+	}
+	return ds[10] == 0
+}
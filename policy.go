@@ -0,0 +1,43 @@
+package walks
+
+// Evaluator decides whether an entry matches a policy.
+// walks embeds no policy language; Evaluator is the integration point for one, eg a compiled CEL program
+// evaluated with the entry's attributes, or an OPA/Rego query with the entry as its input.
+type Evaluator interface {
+	Eval(e Entry) (bool, error)
+}
+
+// EvaluatorFunc adapts a function to an Evaluator.
+type EvaluatorFunc func(e Entry) (bool, error)
+
+// Eval returns f(e).
+func (f EvaluatorFunc) Eval(e Entry) (bool, error) {
+	return f(e)
+}
+
+// Policy routes entries matching Rule to Action, making compliance scanners declarative.
+type Policy struct {
+	Name string
+	Rule Evaluator
+	// Action is called with each matching entry; the entry is a Clone, so it can be kept.
+	Action func(policy string, e Entry)
+}
+
+// EvaluatePolicies walks recursively given directory structure and evaluates every entry against all policies,
+// calling the Action of each matching one. First error, of the walk or of any Rule, stops the evaluation and is returned.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before EvaluatePolicies call.
+func EvaluatePolicies(root string, policies []Policy) error {
+	it := NewIterator(root)
+	for e := it.Next(); e != nil; e = it.Next() {
+		for _, policy := range policies {
+			ok, err := policy.Rule.Eval(*e)
+			if err != nil {
+				return err
+			}
+			if ok && policy.Action != nil {
+				policy.Action(policy.Name, e.Clone())
+			}
+		}
+	}
+	return it.Err()
+}
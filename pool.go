@@ -0,0 +1,58 @@
+package walks
+
+import "sync"
+
+// workQueue is an unbounded queue of tasks for a fixed set of workers.
+// Tasks are taken last in, first out, so that a walk proceeds depth-first and the queue stays short.
+// The queue never blocks on push, so workers can queue more work without deadlocking.
+type workQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	tasks  []func()
+	closed bool
+}
+
+// newWorkQueue returns an empty queue served by n goroutines, that run the queued tasks until the queue is closed.
+func newWorkQueue(n int) *workQueue {
+	q := &workQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	for i := 0; i < n; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// push queues task.
+func (q *workQueue) push(task func()) {
+	q.mu.Lock()
+	q.tasks = append(q.tasks, task)
+	q.mu.Unlock()
+	q.cond.Signal()
+}
+
+// close stops the workers, once they finish their current tasks. Tasks still queued are not run.
+func (q *workQueue) close() {
+	q.mu.Lock()
+	q.closed = true
+	q.mu.Unlock()
+	q.cond.Broadcast()
+}
+
+// work runs queued tasks until the queue is closed.
+func (q *workQueue) work() {
+	for {
+		q.mu.Lock()
+		for len(q.tasks) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		task := q.tasks[len(q.tasks)-1]
+		q.tasks[len(q.tasks)-1] = nil
+		q.tasks = q.tasks[:len(q.tasks)-1]
+		q.mu.Unlock()
+		task()
+	}
+}
@@ -0,0 +1,112 @@
+package walks
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// DefaultPreflightTimeout is the time a root must answer within in Preflight, when Walker's OpTimeout is 0.
+const DefaultPreflightTimeout = 5 * time.Second
+
+// RootCheck is the result of Preflight for one root.
+type RootCheck struct {
+	// Root is the checked root, as given to Preflight.
+	Root string
+	// Exists, IsDir and Readable report whether root exists, is a directory and could be opened and listed (or opened, if it is a file).
+	Exists, IsDir, Readable bool
+	// Latency is the time the checks took, or the timeout if root did not answer in time.
+	Latency time.Duration
+	// Err is the reason a walk of root with the Walker would fail right away (taking its Root policy into account),
+	// nil if it would start: the error of stat, open or listing, a not-a-directory error, or ErrUnreachable when root did not answer in time.
+	Err error
+}
+
+// OK reports whether a walk of the root would start.
+func (c RootCheck) OK() bool {
+	return c.Err == nil
+}
+
+// Preflight checks each of roots before walking them: that it exists, is a directory and is readable, and that it answers within
+// Walker's OpTimeout (DefaultPreflightTimeout if it is 0), so that schedulers can skip or alert on bad roots instead of failing mid-walk.
+// Roots are checked concurrently and results returned in the order of roots. Nothing is created or walked, even under RootCreateMissing.
+// A check hanging in the kernel cannot be cancelled: its goroutine is left behind until the filesystem answers.
+func (w *Walker) Preflight(roots ...string) []RootCheck {
+	timeout := w.OpTimeout
+	if timeout <= 0 {
+		timeout = DefaultPreflightTimeout
+	}
+	results := make([]chan RootCheck, len(roots))
+	for i, root := range roots {
+		results[i] = make(chan RootCheck, 1)
+		go func(root string, result chan<- RootCheck) {
+			result <- w.preflight(root)
+		}(root, results[i])
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	expired := false
+	checks := make([]RootCheck, len(roots))
+	for i, root := range roots {
+		if !expired {
+			select {
+			case checks[i] = <-results[i]:
+				continue
+			case <-timer.C:
+				expired = true
+			}
+		}
+		select {
+		case checks[i] = <-results[i]:
+		default:
+			checks[i] = RootCheck{Root: root, Latency: timeout, Err: &fs.PathError{Op: "preflight", Path: root, Err: ErrUnreachable}}
+		}
+	}
+	return checks
+}
+
+// preflight checks root, see Preflight.
+func (w *Walker) preflight(root string) (check RootCheck) {
+	check.Root = root
+	start := time.Now()
+	defer func() { check.Latency = time.Since(start) }()
+	path := root
+	if w.LongPaths {
+		var err error
+		if path, err = LongPath(root); err != nil {
+			check.Err = err
+			return check
+		}
+	}
+	info, err := w.fsys().Stat(path)
+	if os.IsNotExist(err) && w.Root&RootCreateMissing != 0 {
+		return check
+	} else if err != nil {
+		check.Err = err
+		return check
+	}
+	check.Exists, check.IsDir = true, info.IsDir()
+	if !check.IsDir && !(info.Mode().IsRegular() && w.Root&RootFileAsEntry != 0) {
+		check.Err = fmt.Errorf("walks: root %v is not a directory", root)
+	}
+	f, err := w.fsys().Open(path)
+	if err != nil {
+		if check.Err == nil {
+			check.Err = err
+		}
+		return check
+	}
+	defer f.Close()
+	if d, ok := f.(fs.ReadDirFile); ok && check.IsDir {
+		if _, err := d.ReadDir(1); err != nil && err != io.EOF {
+			if check.Err == nil {
+				check.Err = err
+			}
+			return check
+		}
+	}
+	check.Readable = true
+	return check
+}
@@ -0,0 +1,202 @@
+package walks
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// quarantineIndex is the name of the index of a quarantine directory, a JSON record per line.
+const quarantineIndex = "index.jsonl"
+
+// QuarantineRecord describes a quarantined file. It is stored as a sidecar next to the file (<ID>.json) and in the quarantine index.
+type QuarantineRecord struct {
+	ID          string      `json:"id"`
+	Path        string      `json:"path"` // original absolute path
+	Rule        string      `json:"rule"` // rule or detector that flagged the file
+	SHA256      string      `json:"sha256"`
+	Size        int64       `json:"size"`
+	Mode        os.FileMode `json:"mode"` // original mode, restored by Restore
+	Quarantined time.Time   `json:"quarantined"`
+}
+
+// Quarantine moves files flagged by scanners (see ScanYara and ScanPII) into directory Dir,
+// with a metadata sidecar and an index for later review and restore. Quarantined files are made read-only.
+// Quarantine is safe for concurrent use.
+type Quarantine struct {
+	Dir string
+	// Walker, if not nil, must grant CanWrite and CanDelete for quarantining and restoring.
+	Walker *Walker
+
+	mu sync.Mutex
+}
+
+// NewQuarantine returns a Quarantine moving files into dir.
+func NewQuarantine(dir string) *Quarantine {
+	return &Quarantine{Dir: dir}
+}
+
+// Add quarantines file path flagged by rule.
+func (q *Quarantine) Add(path string, rule string) (QuarantineRecord, error) {
+	if err := q.Walker.Require(CanWrite|CanDelete, "quarantine", path); err != nil {
+		return QuarantineRecord{}, err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	info, err := DefaultFS.Lstat(abs)
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	hash, err := hashFile(abs)
+	if err != nil {
+		return QuarantineRecord{}, err
+	}
+	sum := hex.EncodeToString(hash)
+	now := DefaultClock.Now().UTC()
+	rec := QuarantineRecord{
+		ID:   now.Format(stageLayout) + "-" + sum[:12],
+		Path: abs, Rule: rule, SHA256: sum, Size: info.Size(), Mode: info.Mode(), Quarantined: now,
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dst := filepath.Join(q.Dir, rec.ID)
+	if err := moveFile(abs, dst); err != nil {
+		return QuarantineRecord{}, err
+	}
+	if err := os.Chmod(dst, 0400); err != nil {
+		return rec, err
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return rec, err
+	}
+	if err := os.WriteFile(dst+".json", append(data, '\n'), 0600); err != nil {
+		return rec, err
+	}
+	index, err := os.OpenFile(filepath.Join(q.Dir, quarantineIndex), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return rec, err
+	}
+	if _, err := index.Write(append(data, '\n')); err != nil {
+		index.Close()
+		return rec, err
+	}
+	return rec, index.Close()
+}
+
+// AddYara quarantines the files of matches, recording the first matching rule of each.
+// All matches are attempted; the first error is returned.
+func (q *Quarantine) AddYara(matches []YaraMatch) error {
+	var firstErr error
+	for _, m := range matches {
+		rule := ""
+		if len(m.Rules) > 0 {
+			rule = m.Rules[0]
+		}
+		if _, err := q.Add(m.Path, "yara:"+rule); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// AddPII quarantines the files of findings, recording the detector of the first finding of each file.
+// All findings are attempted; the first error is returned.
+func (q *Quarantine) AddPII(findings []PIIFinding) error {
+	var firstErr error
+	done := make(map[string]bool)
+	for _, f := range findings {
+		if done[f.Path] {
+			continue
+		}
+		done[f.Path] = true
+		if _, err := q.Add(f.Path, "pii:"+f.Detector); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Records returns the records of files currently in quarantine, oldest first.
+func (q *Quarantine) Records() ([]QuarantineRecord, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.records()
+}
+
+// records reads the index, leaving out files restored since.
+func (q *Quarantine) records() ([]QuarantineRecord, error) {
+	f, err := os.Open(filepath.Join(q.Dir, quarantineIndex))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var recs []QuarantineRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var rec QuarantineRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, err
+		}
+		if _, err := DefaultFS.Lstat(filepath.Join(q.Dir, rec.ID)); err == nil {
+			recs = append(recs, rec)
+		}
+	}
+	return recs, scanner.Err()
+}
+
+// Restore moves quarantined file id back to its original path with its original mode, and removes it from the index.
+// A file occupying the original path again is not overwritten.
+func (q *Quarantine) Restore(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	recs, err := q.records()
+	if err != nil {
+		return err
+	}
+	var rec *QuarantineRecord
+	var kept []QuarantineRecord
+	for i := range recs {
+		if recs[i].ID == id {
+			rec = &recs[i]
+		} else {
+			kept = append(kept, recs[i])
+		}
+	}
+	if rec == nil {
+		return fmt.Errorf("walks: %v is not in quarantine", id)
+	}
+	if err := q.Walker.Require(CanWrite|CanDelete, "restore", rec.Path); err != nil {
+		return err
+	}
+	if _, err := DefaultFS.Lstat(rec.Path); err == nil {
+		return fmt.Errorf("walks: restore of %v: %w", rec.Path, os.ErrExist)
+	}
+	src := filepath.Join(q.Dir, id)
+	if err := moveFile(src, rec.Path); err != nil {
+		return err
+	}
+	if err := os.Chmod(rec.Path, rec.Mode.Perm()); err != nil {
+		return err
+	}
+	os.Remove(src + ".json")
+	return WriteFileAtomic(filepath.Join(q.Dir, quarantineIndex), 0600, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		for _, r := range kept {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
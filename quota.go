@@ -0,0 +1,161 @@
+package walks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQuota is returned (wrapped, naming the limit) by a walk exceeding one of Walker's Quota limits.
+var ErrQuota = errors.New("walks: walk quota exceeded")
+
+// Quota limits the resources one walk may use, so that a service running walks for many tenants
+// (each with its own Walker) can keep one tenant's huge tree from starving the others. Zero fields mean no limit.
+type Quota struct {
+	// MaxWorkers limits the number of directories listed at the same time. Unlike Walker's MaxWorkers, it does not bound
+	// the number of goroutines of the walk, which wait for their turn instead; with both set, the smaller one holds.
+	MaxWorkers int
+	// MaxPending limits the number of directories waiting to be listed, which bounds the memory held by the walk's queue;
+	// exceeding it fails the walk.
+	MaxPending int
+	// Rate limits the number of entries processed per second; the walk is slowed down to it.
+	Rate float64
+	// MaxEntries limits the total number of entries processed; exceeding it fails the walk.
+	MaxEntries int64
+	// MaxDuration limits the time the walk may take, as a hint for CPU time spent; exceeding it fails the walk.
+	MaxDuration time.Duration
+}
+
+// QuotaUsage reports the resources used by the last walk of a Walker, see Walker's Usage.
+type QuotaUsage struct {
+	Entries     int64         // entries processed
+	PeakWorkers int           // most directories listed at the same time
+	PeakPending int           // most directories waiting to be listed
+	Throttled   time.Duration // total time entries were delayed by Rate
+	Elapsed     time.Duration // duration of the walk
+}
+
+// limiter enforces a Quota during one walk.
+type limiter struct {
+	quota Quota
+	sem   chan struct{}
+	clock Clock
+	start time.Time
+
+	entries int64 // atomic
+	mu      sync.Mutex
+	workers int
+	pending int
+	next    time.Time // earliest time of the next entry under Rate
+	usage   QuotaUsage
+}
+
+// newLimiter returns a limiter enforcing quota, starting now as told by clock.
+func newLimiter(quota Quota, clock Clock) *limiter {
+	l := &limiter{quota: quota, clock: clock, start: clock.Now()}
+	if quota.MaxWorkers > 0 {
+		l.sem = make(chan struct{}, quota.MaxWorkers)
+	}
+	return l
+}
+
+// acquire waits until a directory may be listed; the returned function must be called when the listing is done.
+func (l *limiter) acquire(ctx context.Context) (release func(), err error) {
+	l.mu.Lock()
+	l.pending++
+	if l.pending > l.usage.PeakPending {
+		l.usage.PeakPending = l.pending
+	}
+	pending := l.pending
+	l.mu.Unlock()
+	if l.quota.MaxPending > 0 && pending > l.quota.MaxPending {
+		l.leave(false)
+		return nil, fmt.Errorf("%w: more than %v directories pending", ErrQuota, l.quota.MaxPending)
+	}
+	if l.sem != nil {
+		select {
+		case l.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.leave(false)
+			return nil, ctx.Err()
+		}
+	}
+	l.mu.Lock()
+	l.pending--
+	l.workers++
+	if l.workers > l.usage.PeakWorkers {
+		l.usage.PeakWorkers = l.workers
+	}
+	l.mu.Unlock()
+	return func() {
+		l.leave(true)
+		if l.sem != nil {
+			<-l.sem
+		}
+	}, nil
+}
+
+// leave removes a pending directory, or a working one if working is set.
+func (l *limiter) leave(working bool) {
+	l.mu.Lock()
+	if working {
+		l.workers--
+	} else {
+		l.pending--
+	}
+	l.mu.Unlock()
+}
+
+// entry accounts for one processed entry, delaying it if needed to keep Rate.
+func (l *limiter) entry(ctx context.Context) error {
+	n := atomic.AddInt64(&l.entries, 1)
+	if l.quota.MaxEntries > 0 && n > l.quota.MaxEntries {
+		return fmt.Errorf("%w: more than %v entries", ErrQuota, l.quota.MaxEntries)
+	}
+	now := l.clock.Now()
+	if l.quota.MaxDuration > 0 && now.Sub(l.start) > l.quota.MaxDuration {
+		return fmt.Errorf("%w: walk took longer than %v", ErrQuota, l.quota.MaxDuration)
+	}
+	if l.quota.Rate <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	wait := l.next.Sub(now)
+	l.next = l.next.Add(time.Duration(float64(time.Second) / l.quota.Rate))
+	l.usage.Throttled += wait
+	l.mu.Unlock()
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish returns the usage of the walk.
+func (l *limiter) finish() QuotaUsage {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	usage := l.usage
+	usage.Entries = atomic.LoadInt64(&l.entries)
+	usage.Elapsed = l.clock.Now().Sub(l.start)
+	return usage
+}
+
+// Usage returns the resources used by the last finished walk of w, to report tenants' consumption against their Quota.
+func (w *Walker) Usage() QuotaUsage {
+	w.usageMu.Lock()
+	defer w.usageMu.Unlock()
+	return w.usage
+}
@@ -0,0 +1,121 @@
+package walks
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// shellSafe reports whether path consists only of characters, that need no quoting in POSIX shells.
+func shellSafe(path string) bool {
+	if path == "" {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("/._-+,:@%", c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// powerShellSafe reports whether path consists only of characters, that need no quoting in PowerShell.
+// It is stricter than shellSafe: ',' makes an array, '@' starts splatting and array or hash literals, and a leading '-' makes a parameter name.
+func powerShellSafe(path string) bool {
+	if path == "" || path[0] == '-' {
+		return false
+	}
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte(`/\._-+:`, c) >= 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// ShellQuote is a PathEncoder that quotes path for POSIX shells, so that `walks ... | while read -r p; do eval "rm $p"; done` style pipelines keep working with spaces and special characters.
+// Paths needing no quoting are returned as they are, others are put in single quotes;
+// paths with control characters or invalid UTF-8 use $'...' quoting (bash, ksh, zsh and POSIX.1-2024 sh), so the result is always a single line.
+func ShellQuote(path string) string {
+	if shellSafe(path) {
+		return path
+	}
+	if !HasControl(path) && utf8.ValidString(path) {
+		return "'" + strings.Replace(path, "'", `'\''`, -1) + "'"
+	}
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '\\' || c == '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c < 0x20 || c == 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		case c >= 0x80:
+			r, size := utf8.DecodeRuneInString(path[i:])
+			if r == utf8.RuneError && size == 1 {
+				fmt.Fprintf(&b, `\x%02x`, c)
+				continue
+			}
+			b.WriteString(path[i : i+size])
+			i += size - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteString("'")
+	return b.String()
+}
+
+// PowerShellQuote is a PathEncoder that quotes path for PowerShell.
+// Paths needing no quoting are returned as they are, others are put in single quotes;
+// paths with control characters use double quotes with backtick escapes, so the result is always a single line.
+func PowerShellQuote(path string) string {
+	if powerShellSafe(path) {
+		return path
+	}
+	if !HasControl(path) {
+		// PowerShell also treats typographic quotes as single quotes.
+		return "'" + strings.NewReplacer("'", "''", "\u2018", "\u2018\u2018", "\u2019", "\u2019\u2019", "\u201a", "\u201a\u201a", "\u201b", "\u201b\u201b").Replace(path) + "'"
+	}
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range path {
+		switch r {
+		case '`', '$', '"', '\u201c', '\u201d', '\u201e':
+			b.WriteRune('`')
+			b.WriteRune(r)
+		case 0:
+			b.WriteString("`0")
+		case '\a':
+			b.WriteString("`a")
+		case '\b':
+			b.WriteString("`b")
+		case '\f':
+			b.WriteString("`f")
+		case '\n':
+			b.WriteString("`n")
+		case '\r':
+			b.WriteString("`r")
+		case '\t':
+			b.WriteString("`t")
+		case '\v':
+			b.WriteString("`v")
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, "`u{%x}", r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package walks
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"a/b.txt", "a/b.txt"},
+		{"a,b", "a,b"},
+		{"@x", "@x"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"a\nb", `$'a\nb'`},
+		{"a\xffb", `$'a\xffb'`},
+	}
+	for _, tt := range tests {
+		if got := ShellQuote(tt.path); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestPowerShellQuote(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{`C:\dir\b.txt`, `C:\dir\b.txt`},
+		{"a-b", "a-b"},
+		{"a,b", "'a,b'"},
+		{"@x", "'@x'"},
+		{"-x", "'-x'"},
+		{"$x", "'$x'"},
+		{"{a}", "'{a}'"},
+		{"(a)", "'(a)'"},
+		{"", "''"},
+		{"it's", "'it''s'"},
+		{"a\nb$", "\"a`nb`$\""},
+	}
+	for _, tt := range tests {
+		if got := PowerShellQuote(tt.path); got != tt.want {
+			t.Errorf("PowerShellQuote(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package walks
+
+import (
+	"errors"
+	"os"
+)
+
+// StrictReadOnly disables all mutating functionality of walks, so that auditors can show a scan could not have modified the walked tree:
+// functions that would create, write, move or remove files or directories fail with an error wrapping ErrReadOnly instead.
+// Files are only ever opened for reading, and on Linux OSFS opens them with O_NOATIME where permitted, so that reading does not even update access times.
+// Set it before walking, like Ignore.
+var StrictReadOnly bool
+
+// ErrReadOnly is the reason of errors returned by mutating functions while StrictReadOnly is set.
+var ErrReadOnly = errors.New("walks: read-only mode")
+
+// writable returns an error for operation op on path while StrictReadOnly is set.
+func writable(op string, path string) error {
+	if StrictReadOnly {
+		return &os.PathError{Op: op, Path: path, Err: ErrReadOnly}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package walks
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RemoveMode selects what Remover does with removed files.
+type RemoveMode int
+
+const (
+	// DryRun only reports the files, that would be removed.
+	DryRun RemoveMode = iota
+	// Trash moves files into Remover's TrashDir, preserving their paths relative to Remover's Root.
+	Trash
+	// Delete deletes files permanently.
+	Delete
+	// Stage moves files into a timestamped staging directory under Remover's TrashDir (see Stage), preserving their paths relative to Remover's Root,
+	// so that the whole cleanup can be undone with Restore or made permanent with Purge.
+	Stage
+)
+
+// Remover removes files according to its Mode; it is the common end of cleanup workflows (eg executing a DeletePlan).
+type Remover struct {
+	Mode RemoveMode
+	// Root is the directory, relative to which removed files' paths are preserved in TrashDir.
+	Root string
+	// TrashDir is the directory, where files are moved to in Trash mode.
+	TrashDir string
+	// Out receives a line for every removed file (prefixed with "would remove" in dry run); it can be nil.
+	Out io.Writer
+	// Walker, if not nil, must grant CanDelete (and CanWrite in Trash and Stage modes) for removals.
+	Walker *Walker
+	// Locks controls removals of files locked by other processes (not checked in dry run).
+	Locks LockPolicy
+
+	stageOnce sync.Once
+	stage     string
+	stageErr  error
+}
+
+// Remove removes file at path according to Remover's Mode.
+// Files locked by other processes are handled according to Remover's Locks; skipped ones are reported to Out.
+func (r *Remover) Remove(path string) error {
+	if r.Mode == DryRun {
+		return r.remove(path)
+	}
+	policy := r.Locks
+	if report := policy.Report; r.Out != nil {
+		policy.Report = func(err *LockedError) {
+			if policy.Skip {
+				r.report("skipped locked %v\n", path)
+			}
+			if report != nil {
+				report(err)
+			}
+		}
+	}
+	return policy.Do(path, func() error { return r.remove(path) })
+}
+
+// remove removes file at path according to Remover's Mode.
+func (r *Remover) remove(path string) error {
+	switch r.Mode {
+	case DryRun:
+		r.report("would remove %v\n", path)
+		return nil
+	case Trash:
+		rel, err := r.rel(path)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(r.TrashDir, rel)
+		if err := r.Walker.Require(CanWrite|CanDelete, "trash", path); err != nil {
+			return err
+		}
+		if err := moveFile(path, dst); err != nil {
+			return err
+		}
+		r.report("trashed %v\n", path)
+		return nil
+	case Stage:
+		rel, err := r.rel(path)
+		if err != nil {
+			return err
+		}
+		if err := r.Walker.Require(CanWrite|CanDelete, "stage", path); err != nil {
+			return err
+		}
+		stage, err := r.Stage()
+		if err != nil {
+			return err
+		}
+		if err := moveFile(path, filepath.Join(r.TrashDir, stage, rel)); err != nil {
+			return err
+		}
+		r.report("staged %v\n", path)
+		return nil
+	case Delete:
+		if err := writable("remove", path); err != nil {
+			return err
+		}
+		if err := r.Walker.Require(CanDelete, "remove", path); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		r.report("removed %v\n", path)
+		return nil
+	}
+	return fmt.Errorf("walks: invalid remove mode %v", r.Mode)
+}
+
+// rel returns path relative to Root, failing when path is not under Root, so that it would be moved out of TrashDir.
+func (r *Remover) rel(path string) (string, error) {
+	rel, err := filepath.Rel(r.Root, path)
+	if err != nil {
+		return "", err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("walks: %v is not under root %v", path, r.Root)
+	}
+	return rel, nil
+}
+
+// report writes a formatted line to Out, if it is set.
+func (r *Remover) report(format string, args ...interface{}) {
+	if r.Out != nil {
+		fmt.Fprintf(r.Out, format, args...)
+	}
+}
+
+// moveFile moves file src to dst, creating missing parent directories of dst.
+// When src and dst are on different devices, file is copied and then removed.
+func moveFile(src string, dst string) error {
+	if err := writable("move", src); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
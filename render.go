@@ -0,0 +1,99 @@
+package walks
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// RenderTree walks recursively template directory srcTemplateDir and recreates it under dst.
+// Both the path names and the file contents are rendered through text/template with given data,
+// so that a template directory like `{{.Name}}/cmd/{{.Name}}.go` can be used to scaffold projects.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before RenderTree call.
+func RenderTree(srcTemplateDir string, dst string, data interface{}) error {
+	if err := writable("render", dst); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	var firstErr error
+	target := func(path string) (string, error) {
+		rel, err := filepath.Rel(srcTemplateDir, path)
+		if err != nil {
+			return "", err
+		}
+		rendered, err := renderString(rel, rel, data)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(dst, rendered), nil
+	}
+	dirAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		out, err := target(path)
+		if err == nil {
+			err = os.MkdirAll(out, 0755)
+		}
+		firstErr = err
+	}
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		firstErr = renderFile(path, target, data)
+	}
+	if _, err := WalkLinearE(srcTemplateDir, fileAction, dirAction, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// RenderTree renders template directory srcTemplateDir under dst like package's RenderTree does, if w grants CanRead and CanWrite (see Require).
+func (w *Walker) RenderTree(srcTemplateDir string, dst string, data interface{}) error {
+	if err := w.Require(CanRead|CanWrite, "render", dst); err != nil {
+		return err
+	}
+	return RenderTree(srcTemplateDir, dst, data)
+}
+
+// renderFile renders template file path into the location given by target, keeping the permissions of the template file.
+func renderFile(path string, target func(string) (string, error), data interface{}) error {
+	out, err := target(path)
+	if err != nil {
+		return err
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	rendered, err := renderString(path, string(contents), data)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	// parent is not created by dirAction, when it does not match Search.
+	if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(out, []byte(rendered), info.Mode().Perm())
+}
+
+// renderString executes text as a template with given data.
+func renderString(name string, text string, data interface{}) (string, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,83 @@
+package walks
+
+import (
+	"bytes"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// ReportData holds the results of a walk rendered in a report. Fields not set are left out of DefaultReport.
+type ReportData struct {
+	Title    string
+	Root     string
+	Taken    time.Time
+	Stats    *DirStats       // summary of the walked root
+	Top      []SnapshotEntry // largest files, see Largest
+	Changes  []Event         // changes since the previous walk, see Snapshot.Diff
+	Alerts   []GrowthAlert
+	Findings []string // audit findings, one per line
+}
+
+// DefaultReport is the template used by Reporter when its Template is nil.
+var DefaultReport = template.Must(template.New("report").Parse(`{{.Title}}{{if .Root}} ({{.Root}}{{if not .Taken.IsZero}}, {{.Taken.Format "2006-01-02 15:04"}}{{end}}){{end}}
+{{with .Stats}}
+{{.TotalFiles}} files, {{.TotalDirs}} directories, {{.TotalBytes}} bytes
+{{end}}{{with .Top}}
+Largest files:
+{{range .}}  {{.Size}}	{{.Path}}
+{{end}}{{end}}{{with .Changes}}
+Changes:
+{{range .}}  {{.Op}}	{{.Path}}
+{{end}}{{end}}{{with .Alerts}}
+Alerts:
+{{range .}}  {{.}}
+{{end}}{{end}}{{with .Findings}}
+Findings:
+{{range .}}  {{.}}
+{{end}}{{end}}`))
+
+// Delivery delivers a rendered report. Email and Slack deliveries are in package notify.
+type Delivery interface {
+	Deliver(subject string, body string) error
+}
+
+// Reporter renders walk results with a template and delivers them, eg on completion of scheduled walks.
+type Reporter struct {
+	// Template renders ReportData; nil means DefaultReport.
+	Template *template.Template
+	Delivery Delivery
+}
+
+// Send renders data and delivers it, with data's Title as the subject.
+func (r *Reporter) Send(data ReportData) error {
+	tmpl := r.Template
+	if tmpl == nil {
+		tmpl = DefaultReport
+	}
+	var body bytes.Buffer
+	if err := tmpl.Execute(&body, data); err != nil {
+		return err
+	}
+	return r.Delivery.Deliver(data.Title, body.String())
+}
+
+// Largest returns the n largest regular files of snap, largest first.
+func Largest(snap *Snapshot, n int) []SnapshotEntry {
+	var files []SnapshotEntry
+	for _, entry := range snap.Entries {
+		if entry.Mode.IsRegular() {
+			files = append(files, entry)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Size != files[j].Size {
+			return files[i].Size > files[j].Size
+		}
+		return files[i].Path < files[j].Path
+	})
+	if len(files) > n {
+		files = files[:n]
+	}
+	return files
+}
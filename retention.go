@@ -0,0 +1,136 @@
+package walks
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetentionRule declares how long files under one directory are kept, eg "under /var/log keep 30 days" or "under builds/ keep last 10 per prefix".
+type RetentionRule struct {
+	// Under is the directory the rule applies to. Each file is governed by the rule with the longest matching Under.
+	Under string
+	// Match, if not empty, limits the rule to files whose base name matches it (see filepath.Match).
+	Match string
+	// MaxAge deletes files modified longer than MaxAge ago; 0 means no age limit.
+	MaxAge time.Duration
+	// KeepLast keeps only the KeepLast newest files in each group; 0 means no count limit.
+	KeepLast int
+	// Group returns the group of file path for KeepLast.
+	// When nil, files are grouped by directory and the part of the base name before the first digit (eg "build-" for "build-123.tar").
+	Group func(path string) string
+}
+
+// DeletePlan lists files to delete, as produced by Retention.
+type DeletePlan struct {
+	Paths   []string          // files to delete, sorted
+	Reasons map[string]string // why each file is deleted
+	Bytes   int64             // total size of the files
+}
+
+// Execute removes all files in the plan with Remover r; first error stops the execution and is returned.
+func (p DeletePlan) Execute(r *Remover) error {
+	for _, path := range p.Paths {
+		if err := r.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// retained is a walked file governed by a retention rule.
+type retained struct {
+	path string
+	info os.FileInfo
+}
+
+// Retention walks recursively given directory structure, evaluates rules on every file and returns the plan of files to delete.
+// Files not under any rule's directory are kept.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before Retention call.
+func Retention(root string, rules []RetentionRule) (DeletePlan, error) {
+	now := DefaultClock.Now()
+	plan := DeletePlan{Reasons: make(map[string]string)}
+	groups := make(map[*RetentionRule]map[string][]retained)
+	var firstErr error
+	fileAction := func(path string) {
+		rule := retentionRule(rules, path)
+		if rule == nil {
+			return
+		}
+		info, err := DefaultFS.Stat(path)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		if rule.MaxAge > 0 && now.Sub(info.ModTime()) > rule.MaxAge {
+			plan.Reasons[path] = "older than " + rule.MaxAge.String()
+			plan.Paths = append(plan.Paths, path)
+			plan.Bytes += info.Size()
+			return
+		}
+		if rule.KeepLast > 0 {
+			group := retentionGroup(path)
+			if rule.Group != nil {
+				group = rule.Group(path)
+			}
+			if groups[rule] == nil {
+				groups[rule] = make(map[string][]retained)
+			}
+			groups[rule][group] = append(groups[rule][group], retained{path: path, info: info})
+		}
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	for rule, byGroup := range groups {
+		for group, files := range byGroup {
+			if len(files) <= rule.KeepLast {
+				continue
+			}
+			sort.Slice(files, func(i, j int) bool { return files[i].info.ModTime().After(files[j].info.ModTime()) })
+			for _, file := range files[rule.KeepLast:] {
+				plan.Reasons[file.path] = "not among last " + strconv.Itoa(rule.KeepLast) + " of " + group
+				plan.Paths = append(plan.Paths, file.path)
+				plan.Bytes += file.info.Size()
+			}
+		}
+	}
+	sort.Strings(plan.Paths)
+	return plan, firstErr
+}
+
+// retentionRule returns the rule with the longest Under governing path, or nil if there is none.
+func retentionRule(rules []RetentionRule, path string) *RetentionRule {
+	var best *RetentionRule
+	path = filepath.Clean(path)
+	for i := range rules {
+		rule := &rules[i]
+		under := filepath.Clean(rule.Under)
+		if !strings.HasPrefix(path, under+string(filepath.Separator)) && under != "." {
+			continue
+		}
+		if rule.Match != "" {
+			if ok, _ := filepath.Match(rule.Match, filepath.Base(path)); !ok {
+				continue
+			}
+		}
+		if best == nil || len(under) > len(filepath.Clean(best.Under)) {
+			best = rule
+		}
+	}
+	return best
+}
+
+// retentionGroup returns the default KeepLast group of path: its directory and the part of the base name before the first digit.
+func retentionGroup(path string) string {
+	name := filepath.Base(path)
+	if i := strings.IndexAny(name, "0123456789"); i >= 0 {
+		name = name[:i]
+	}
+	return filepath.Join(filepath.Dir(path), name)
+}
@@ -0,0 +1,26 @@
+package walks
+
+import "path/filepath"
+
+// DefaultRoute is the key of the route taken by files whose extension has no route of its own, see Route.
+const DefaultRoute = "*"
+
+// Route returns a fileAction for w's WalkEntries, dispatching each file to the action routed to its extension
+// (as returned by filepath.Ext, eg ".go", or "" for files without one), so that processors of many formats do not need one action branching on names.
+// Files of extensions without a route go to routes[DefaultRoute], or are passed over if there is none.
+// Extensions are matched exactly (".JPG" is not ".jpg"); routes is copied, so changing it later has no effect.
+func (w *Walker) Route(routes map[string]func(Entry)) func(Entry) error {
+	table := make(map[string]func(Entry), len(routes))
+	for ext, action := range routes {
+		table[ext] = action
+	}
+	def := table[DefaultRoute]
+	return func(e Entry) error {
+		if action, ok := table[filepath.Ext(e.Name())]; ok {
+			action(e)
+		} else if def != nil {
+			def(e)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,41 @@
+//go:build go1.23
+// +build go1.23
+
+package walks
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator over the walk of given directory structure with Walker's configuration, for use with range:
+//
+//	for e, err := range w.All(ctx, root) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The walk is done in Ordered mode, as entries are yielded one at a time; breaking out of the loop stops it.
+// A failed walk yields its error last, with a zero Entry. Yielded entries can be kept.
+func (w *Walker) All(ctx context.Context, root string) iter.Seq2[Entry, error] {
+	return func(yield func(Entry, error) bool) {
+		action := func(e Entry) error {
+			if !yield(e, nil) {
+				return SkipAll
+			}
+			return nil
+		}
+		defer w.cleanTemp()
+		if err := w.run(ctx, root, action, action, true); err != nil {
+			yield(Entry{}, err)
+		}
+	}
+}
+
+// All returns an iterator over the walk of given directory structure, like Walker's All does, using package's Ignore and Search.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before All call.
+func All(root string) iter.Seq2[Entry, error] {
+	return New().All(context.Background(), root)
+}
@@ -0,0 +1,164 @@
+package walks
+
+import (
+	"hash/fnv"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// shardCount is the number of shards in ShardedMap and Appender.
+const shardCount = 32
+
+// shardOf returns the index of the shard for key.
+func shardOf(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % shardCount)
+}
+
+// ShardedMap is a map safe for concurrent use by actions.
+// Keys are spread over several independently locked shards, so concurrent actions rarely wait for each other.
+// Zero value is an empty map ready to use.
+type ShardedMap struct {
+	shards [shardCount]struct {
+		sync.Mutex
+		m map[string]interface{}
+	}
+}
+
+// Load returns the value stored for key and whether it was present.
+func (m *ShardedMap) Load(key string) (interface{}, bool) {
+	s := &m.shards[shardOf(key)]
+	s.Lock()
+	defer s.Unlock()
+	v, ok := s.m[key]
+	return v, ok
+}
+
+// Store sets the value for key.
+func (m *ShardedMap) Store(key string, v interface{}) {
+	m.Update(key, func(interface{}, bool) interface{} { return v })
+}
+
+// Update atomically replaces the value for key with the value returned by fn, that is given the current value and whether it was present.
+// The new value is returned.
+// fn must not use the map, as the shard of key is locked while fn runs.
+func (m *ShardedMap) Update(key string, fn func(old interface{}, ok bool) interface{}) interface{} {
+	s := &m.shards[shardOf(key)]
+	s.Lock()
+	defer s.Unlock()
+	if s.m == nil {
+		s.m = make(map[string]interface{})
+	}
+	old, ok := s.m[key]
+	v := fn(old, ok)
+	s.m[key] = v
+	return v
+}
+
+// Delete removes key from the map.
+func (m *ShardedMap) Delete(key string) {
+	s := &m.shards[shardOf(key)]
+	s.Lock()
+	defer s.Unlock()
+	delete(s.m, key)
+}
+
+// Len returns the number of keys in the map.
+func (m *ShardedMap) Len() int {
+	var n int
+	for i := range m.shards {
+		m.shards[i].Lock()
+		n += len(m.shards[i].m)
+		m.shards[i].Unlock()
+	}
+	return n
+}
+
+// Range calls fn for each key and value in the map in key order, until fn returns false.
+// Range works on a copy of the map, so fn may use the map.
+func (m *ShardedMap) Range(fn func(key string, v interface{}) bool) {
+	all := make(map[string]interface{})
+	for i := range m.shards {
+		m.shards[i].Lock()
+		for k, v := range m.shards[i].m {
+			all[k] = v
+		}
+		m.shards[i].Unlock()
+	}
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !fn(k, all[k]) {
+			return
+		}
+	}
+}
+
+// Counter is an int64 counter safe for concurrent use by actions.
+// Zero value is a counter with value 0.
+type Counter struct {
+	n int64
+}
+
+// Add adds delta to the counter and returns the new value.
+func (c *Counter) Add(delta int64) int64 {
+	return atomic.AddInt64(&c.n, delta)
+}
+
+// Load returns the current value of the counter.
+func (c *Counter) Load() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+// Appender collects values appended concurrently by actions and returns them ordered by key (eg the path),
+// so that results of a concurrent walk can be reported in a deterministic order.
+// Zero value is an empty Appender ready to use.
+type Appender struct {
+	seq    int64
+	shards [shardCount]struct {
+		sync.Mutex
+		items []appended
+	}
+}
+
+// appended is a value in Appender, with seq recording the order of Append calls.
+type appended struct {
+	key string
+	seq int64
+	v   interface{}
+}
+
+// Append adds value v with given key.
+func (a *Appender) Append(key string, v interface{}) {
+	seq := atomic.AddInt64(&a.seq, 1)
+	s := &a.shards[shardOf(key)]
+	s.Lock()
+	s.items = append(s.items, appended{key: key, seq: seq, v: v})
+	s.Unlock()
+}
+
+// Items returns appended values ordered by key; values with the same key are in the order they were appended.
+func (a *Appender) Items() []interface{} {
+	var all []appended
+	for i := range a.shards {
+		a.shards[i].Lock()
+		all = append(all, a.shards[i].items...)
+		a.shards[i].Unlock()
+	}
+	sort.Slice(all, func(i, j int) bool {
+		if all[i].key != all[j].key {
+			return all[i].key < all[j].key
+		}
+		return all[i].seq < all[j].seq
+	})
+	items := make([]interface{}, len(all))
+	for i, item := range all {
+		items[i] = item.v
+	}
+	return items
+}
@@ -0,0 +1,29 @@
+package walks
+
+// Sink consumes walked files one at a time, eg to store, copy or archive them.
+type Sink interface {
+	// Put consumes file at path.
+	Put(path string) error
+	// Close finishes the work of the sink, after all files have been put.
+	Close() error
+}
+
+// WalkSink walks recursively given directory structure and puts every file to sink, closing the sink at the end.
+// First error returned by the sink stops putting more files and is returned.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before WalkSink call.
+func WalkSink(root string, sink Sink) error {
+	var firstErr error
+	fileAction := func(path string) {
+		if firstErr != nil {
+			return
+		}
+		firstErr = sink.Put(path)
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	if err := sink.Close(); firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
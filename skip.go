@@ -0,0 +1,45 @@
+package walks
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// SkipDir is returned by actions of WalkErr to skip a directory, like with filepath.WalkDir (it is the same value as fs.SkipDir).
+// A dirAction returning it prunes the directory before the walk enters it, so none of its contents are visited (see also Prune).
+var SkipDir = fs.SkipDir
+
+// SkipAll is returned by actions of WalkErr to stop the walk without an error.
+var SkipAll = errors.New("skip everything and stop the walk")
+
+// plainAction adapts an action, that takes a path and cannot fail, to the signature of WalkEntries' actions.
+func plainAction(action func(string)) func(Entry) error {
+	return func(e Entry) error {
+		action(e.Path)
+		return nil
+	}
+}
+
+// errAction adapts an action, that takes a path, to the signature of WalkEntries' actions.
+func errAction(action func(string) error) func(Entry) error {
+	return func(e Entry) error {
+		return action(e.Path)
+	}
+}
+
+// Prune adapts an action, that reports whether the walk should descend into the directory it is given, to a dirAction of WalkEntries:
+// false is returned as SkipDir. The decision can depend on the directory's contents, as the action runs before the walk enters the directory,
+// eg skipping every directory that holds a package.json or a .git:
+//
+//	walks.Prune(func(e walks.Entry) bool {
+//		_, err := os.Lstat(filepath.Join(e.Path, ".git"))
+//		return err != nil
+//	})
+func Prune(action func(Entry) bool) func(Entry) error {
+	return func(e Entry) error {
+		if !action(e) {
+			return SkipDir
+		}
+		return nil
+	}
+}
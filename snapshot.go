@@ -0,0 +1,196 @@
+package walks
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SnapshotEntry is the recorded state of one file or directory in a Snapshot.
+type SnapshotEntry struct {
+	Path    string
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
+}
+
+// IsDir reports whether the entry is a directory.
+func (e SnapshotEntry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// Snapshot is the recorded state of a directory structure at one point in time.
+// Snapshots can be compared with Diff and cheaply refreshed with Refresh.
+type Snapshot struct {
+	Root  string
+	Taken time.Time
+	// Entries holds every recorded entry by its path, including the root.
+	Entries map[string]SnapshotEntry
+
+	children map[string][]string // sorted names of recorded entries in each directory
+}
+
+// snapshotChild is an entry of a directory being recorded.
+type snapshotChild struct {
+	name string
+	info os.FileInfo
+}
+
+// TakeSnapshot walks recursively directory root and records the state of all of its entries.
+// Entries disappearing during the walk are left out.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before TakeSnapshot call.
+func TakeSnapshot(root string) (*Snapshot, error) {
+	return takeSnapshot(root, nil)
+}
+
+// Refresh returns a new snapshot of the same root.
+// Refresh is incremental: directories, whose modification time has not changed since s was taken, are not listed again;
+// only their recorded entries are re-examined (with lstat), which is considerably cheaper on large trees.
+func (s *Snapshot) Refresh() (*Snapshot, error) {
+	return takeSnapshot(s.Root, s)
+}
+
+// takeSnapshot records root, reusing directory listings of prev (if not nil) for unchanged directories.
+func takeSnapshot(root string, prev *Snapshot) (*Snapshot, error) {
+	info, err := DefaultFS.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{Root: root, Taken: DefaultClock.Now(), Entries: make(map[string]SnapshotEntry), children: make(map[string][]string)}
+	s.record(root, info)
+	if !info.IsDir() {
+		return s, nil
+	}
+	var scan func(dir string) error
+	scan = func(dir string) error {
+		children, err := snapshotChildren(dir, s.Entries[dir], prev)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, child := range children {
+			pathName := filepath.Join(dir, child.name)
+			if ignoredPath(pathName, child.info.IsDir()) {
+				continue
+			}
+			s.record(pathName, child.info)
+			names = append(names, child.name)
+			if child.info.IsDir() {
+				if err := scan(pathName); err != nil {
+					return err
+				}
+			}
+		}
+		s.children[dir] = names
+		return nil
+	}
+	return s, scan(root)
+}
+
+// snapshotChildren returns the entries of directory dir, reusing the listing in prev if dir has not changed since.
+func snapshotChildren(dir string, cur SnapshotEntry, prev *Snapshot) ([]snapshotChild, error) {
+	if prev != nil {
+		old, ok := prev.Entries[dir]
+		names, listed := prev.children[dir]
+		if ok && listed && old.IsDir() && old.ModTime.Equal(cur.ModTime) {
+			children := make([]snapshotChild, 0, len(names))
+			for _, name := range names {
+				info, err := DefaultFS.Lstat(filepath.Join(dir, name))
+				if os.IsNotExist(err) {
+					continue
+				} else if err != nil {
+					return nil, err
+				}
+				children = append(children, snapshotChild{name: name, info: info})
+			}
+			return children, nil
+		}
+	}
+	entries, err := DefaultFS.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	children := make([]snapshotChild, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		children = append(children, snapshotChild{name: entry.Name(), info: info})
+	}
+	return children, nil
+}
+
+// record adds entry path described by info to the snapshot.
+func (s *Snapshot) record(path string, info os.FileInfo) {
+	s.Entries[path] = SnapshotEntry{Path: path, Size: info.Size(), Mode: info.Mode(), ModTime: info.ModTime()}
+}
+
+// Paths returns the paths of all recorded entries, sorted.
+func (s *Snapshot) Paths() []string {
+	paths := make([]string, 0, len(s.Entries))
+	for path := range s.Entries {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Children returns the recorded entries directly in directory dir, sorted by name.
+func (s *Snapshot) Children(dir string) []SnapshotEntry {
+	names := s.children[dir]
+	children := make([]SnapshotEntry, 0, len(names))
+	for _, name := range names {
+		children = append(children, s.Entries[filepath.Join(dir, name)])
+	}
+	return children
+}
+
+// Usage returns the total size of regular files at or under path, like `du`.
+func (s *Snapshot) Usage(path string) int64 {
+	path = filepath.Clean(path)
+	var total int64
+	for p, entry := range s.Entries {
+		if entry.Mode.IsRegular() && (p == path || strings.HasPrefix(p, path+string(filepath.Separator))) {
+			total += entry.Size
+		}
+	}
+	return total
+}
+
+// Diff returns the changes turning s into newer, sorted by path: created and deleted entries,
+// and files whose size or modification time changed.
+// Modification times recorded with whole second precision (eg by ncdu) are compared with that precision.
+func (s *Snapshot) Diff(newer *Snapshot) []Event {
+	var events []Event
+	for path, entry := range newer.Entries {
+		old, ok := s.Entries[path]
+		switch {
+		case !ok:
+			events = append(events, Event{Path: path, Op: OpCreate, IsDir: entry.IsDir()})
+		case !entry.IsDir() && (old.Size != entry.Size || !sameTime(old.ModTime, entry.ModTime)):
+			events = append(events, Event{Path: path, Op: OpModify})
+		}
+	}
+	for path, entry := range s.Entries {
+		if _, ok := newer.Entries[path]; !ok {
+			events = append(events, Event{Path: path, Op: OpDelete, IsDir: entry.IsDir()})
+		}
+	}
+	sortEvents(events)
+	return events
+}
+
+// sameTime reports whether modification times a and b are equal, comparing whole seconds if either of them has no fraction.
+func sameTime(a, b time.Time) bool {
+	if a.Nanosecond() == 0 || b.Nanosecond() == 0 {
+		return a.Unix() == b.Unix()
+	}
+	return a.Equal(b)
+}
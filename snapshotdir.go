@@ -0,0 +1,53 @@
+package walks
+
+import "os"
+
+// SnapshotPolicy controls how Walker treats filesystem snapshot directories, which hold further copies of the tree they are in
+// and inflate the results of scanning eg NAS exports many times over (see IsSnapshotDir).
+type SnapshotPolicy int
+
+const (
+	// SnapshotsInclude walks snapshot directories like any other directory.
+	SnapshotsInclude SnapshotPolicy = iota
+	// SnapshotsSkip neither passes snapshot directories to dirAction nor descends into them.
+	SnapshotsSkip
+	// SnapshotsOnly passes only snapshot directories and their contents to the actions; the rest of the tree is still descended into to find them.
+	SnapshotsOnly
+)
+
+// snapshotNames are the names of snapshot directories of common filesystems and NAS appliances.
+var snapshotNames = map[string]bool{
+	".snapshot":  true, // NetApp, Isilon, other NFS filers
+	"~snapshot":  true, // NetApp over SMB
+	".snapshots": true, // snapper on Btrfs
+	"#snapshot":  true, // Synology
+	".zfs":       true, // ZFS control directory, holding snapshot/
+}
+
+// IsSnapshotDir reports whether directory path (described by info) is a snapshot directory:
+// one of the well-known snapshot directory names (.snapshot, ~snapshot, .snapshots, #snapshot, .zfs),
+// or, on Linux, a read-only Btrfs subvolume, as snapshots made by snapper, btrbk or timeshift are.
+func IsSnapshotDir(path string, info os.FileInfo) bool {
+	if !info.IsDir() {
+		return false
+	}
+	return snapshotNames[info.Name()] || btrfsSnapshot(path, info)
+}
+
+// snapshots reports whether directory path (described by info), an entry of node, is to be skipped according to Walker's Snapshots,
+// and whether it is a snapshot directory or inside one.
+func (w *Walker) snapshots(node *dirNode, path string, info os.FileInfo) (skip bool, inside bool) {
+	if w.Snapshots == SnapshotsInclude {
+		return false, false
+	}
+	if node.inSnapshot {
+		return false, true
+	}
+	inside = IsSnapshotDir(path, info)
+	return inside && w.Snapshots == SnapshotsSkip, inside
+}
+
+// acts reports whether an entry (inside a snapshot directory or not) is passed to the actions according to Walker's Snapshots.
+func (w *Walker) acts(inside bool) bool {
+	return w.Snapshots != SnapshotsOnly || inside
+}
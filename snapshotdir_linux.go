@@ -0,0 +1,30 @@
+package walks
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// Btrfs ioctl constants for reading subvolume flags.
+const (
+	btrfsIocSubvolGetFlg = 0x80089419 // _IOR(BTRFS_IOCTL_MAGIC, 25, __u64)
+	btrfsSubvolRdonly    = 1 << 1
+)
+
+// btrfsSnapshot reports whether directory path (described by info) is a read-only Btrfs subvolume.
+func btrfsSnapshot(path string, info os.FileInfo) bool {
+	if _, ino, ok := fileID(info); !ok || ino != btrfsSubvolumeIno || fsType(path) != "btrfs" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	var flags uint64
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), btrfsIocSubvolGetFlg, uintptr(unsafe.Pointer(&flags))); errno != 0 {
+		return false
+	}
+	return flags&btrfsSubvolRdonly != 0
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+import "os"
+
+// btrfsSnapshot reports false, Btrfs exists only on Linux.
+func btrfsSnapshot(path string, info os.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,160 @@
+package walks
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SnapshotFS is an io/fs.FS over a Snapshot: directory listings and file info come from the snapshot, without touching the disk,
+// while opened files are read from the disk (and must have stayed in place since the snapshot was taken).
+// Code written against io/fs can so operate over recorded metadata without walking again; a Walker can walk it with IOFS.
+// Names are relative to the snapshot's root ("." is the root itself), as io/fs requires; entries not in the snapshot do not exist.
+type SnapshotFS struct {
+	Snapshot *Snapshot
+}
+
+// FS returns the io/fs.FS of s (see SnapshotFS).
+func (s *Snapshot) FS() SnapshotFS {
+	return SnapshotFS{Snapshot: s}
+}
+
+// entry returns the recorded entry of name, or an *fs.PathError of op.
+func (f SnapshotFS) entry(op string, name string) (SnapshotEntry, error) {
+	if !fs.ValidPath(name) {
+		return SnapshotEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	path := f.Snapshot.Root
+	if name != "." {
+		path = filepath.Join(path, filepath.FromSlash(name))
+	}
+	entry, ok := f.Snapshot.Entries[path]
+	if !ok {
+		return SnapshotEntry{}, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return entry, nil
+}
+
+// Open opens file name: directories are served from the snapshot, files are opened on the disk.
+func (f SnapshotFS) Open(name string) (fs.File, error) {
+	entry, err := f.entry("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return &snapshotDir{info: snapshotInfo{entry}, entries: f.Snapshot.Children(entry.Path)}, nil
+	}
+	return os.Open(entry.Path)
+}
+
+// Stat returns the recorded info of name.
+func (f SnapshotFS) Stat(name string) (fs.FileInfo, error) {
+	entry, err := f.entry("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return snapshotInfo{entry}, nil
+}
+
+// ReadDir returns the recorded entries of directory name, sorted by name.
+func (f SnapshotFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entry, err := f.entry("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	children := f.Snapshot.Children(entry.Path)
+	entries := make([]fs.DirEntry, len(children))
+	for i, child := range children {
+		entries[i] = snapshotInfo{child}
+	}
+	return entries, nil
+}
+
+// snapshotInfo is the fs.FileInfo and fs.DirEntry of a recorded entry.
+type snapshotInfo struct {
+	e SnapshotEntry
+}
+
+// Name returns the base name of the entry.
+func (i snapshotInfo) Name() string {
+	return filepath.Base(i.e.Path)
+}
+
+// Size returns the recorded size of the entry.
+func (i snapshotInfo) Size() int64 {
+	return i.e.Size
+}
+
+// Mode returns the recorded mode of the entry.
+func (i snapshotInfo) Mode() fs.FileMode {
+	return i.e.Mode
+}
+
+// ModTime returns the recorded modification time of the entry.
+func (i snapshotInfo) ModTime() time.Time {
+	return i.e.ModTime
+}
+
+// IsDir reports whether the entry is a directory.
+func (i snapshotInfo) IsDir() bool {
+	return i.e.IsDir()
+}
+
+// Sys returns nil, snapshots do not record system specific info.
+func (i snapshotInfo) Sys() interface{} {
+	return nil
+}
+
+// Type returns the type bits of the entry.
+func (i snapshotInfo) Type() fs.FileMode {
+	return i.e.Mode.Type()
+}
+
+// Info returns the entry itself.
+func (i snapshotInfo) Info() (fs.FileInfo, error) {
+	return i, nil
+}
+
+// snapshotDir is an open directory of a SnapshotFS.
+type snapshotDir struct {
+	info    snapshotInfo
+	entries []SnapshotEntry
+	next    int
+}
+
+// Stat returns the recorded info of the directory.
+func (d *snapshotDir) Stat() (fs.FileInfo, error) {
+	return d.info, nil
+}
+
+// Read fails, directories cannot be read.
+func (d *snapshotDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.e.Path, Err: fs.ErrInvalid}
+}
+
+// Close does nothing, as there is nothing to release.
+func (d *snapshotDir) Close() error {
+	return nil
+}
+
+// ReadDir returns the next n entries of the directory (all remaining ones if n <= 0), like fs.ReadDirFile.
+func (d *snapshotDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.next:]
+	if n > 0 && len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > 0 && n < len(rest) {
+		rest = rest[:n]
+	}
+	d.next += len(rest)
+	entries := make([]fs.DirEntry, len(rest))
+	for i, e := range rest {
+		entries[i] = snapshotInfo{e}
+	}
+	return entries, nil
+}
@@ -0,0 +1,62 @@
+package walks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrInsufficientSpace is returned (wrapped, with the numbers) by copying functions, when their destination does not have enough free space.
+var ErrInsufficientSpace = errors.New("walks: insufficient space on destination")
+
+// SpaceMargin is the free space, that copying functions (CopyTree, CAS, moving between devices) leave untouched on the destination.
+var SpaceMargin int64 = 64 << 20
+
+// FreeSpace returns the number of bytes available to unprivileged users on the filesystem of path,
+// or -1 if it cannot be determined on this platform.
+func FreeSpace(path string) (int64, error) {
+	return freeSpace(path)
+}
+
+// CheckSpace returns an error wrapping ErrInsufficientSpace, unless writing need bytes under dst leaves at least SpaceMargin free.
+// dst does not need to exist yet, free space of its nearest existing ancestor is checked.
+// On platforms where free space cannot be determined, nil is returned.
+func CheckSpace(dst string, need int64) error {
+	dir := filepath.Clean(dst)
+	for {
+		if _, err := os.Stat(dir); err == nil || filepath.Dir(dir) == dir {
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+	free, err := freeSpace(dir)
+	if err != nil {
+		return err
+	}
+	if free >= 0 && need+SpaceMargin > free {
+		return fmt.Errorf("%w: %v needs %v bytes (and %v margin), %v available", ErrInsufficientSpace, dst, need, SpaceMargin, free)
+	}
+	return nil
+}
+
+// TreeSize returns the total size of regular files under root, eg to check the space needed for copying it before starting.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before TreeSize call.
+func TreeSize(root string) (int64, error) {
+	var total int64
+	var firstErr error
+	fileAction := func(path string) {
+		info, err := DefaultFS.Lstat(path)
+		if err != nil {
+			if firstErr == nil && !os.IsNotExist(err) {
+				firstErr = err
+			}
+			return
+		}
+		total += info.Size()
+	}
+	if _, err := WalkLinearE(root, fileAction, func(string) {}, -1, 0); firstErr == nil {
+		firstErr = err
+	}
+	return total, firstErr
+}
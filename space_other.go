@@ -0,0 +1,9 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux
+// +build !darwin,!dragonfly,!freebsd,!linux
+
+package walks
+
+// freeSpace returns -1, free space cannot be determined on this platform.
+func freeSpace(path string) (int64, error) {
+	return -1, nil
+}
@@ -0,0 +1,163 @@
+package walks
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stageRootFile records, in each staging directory, the Root files were staged from.
+const stageRootFile = ".walks-root"
+
+// stageLayout is the time format of staging directory names; it sorts chronologically.
+const stageLayout = "20060102T150405.000000000Z"
+
+// Stage returns the name of the staging directory under TrashDir used by the Remover in Stage mode, creating it on first call.
+// The name is the UTC time of the first call, so each Remover stages one reversible cleanup.
+func (r *Remover) Stage() (string, error) {
+	r.stageOnce.Do(func() {
+		stage := r.Walker.clock().Now().UTC().Format(stageLayout)
+		dir := filepath.Join(r.TrashDir, stage)
+		if r.stageErr = writable("mkdir", dir); r.stageErr != nil {
+			return
+		}
+		if r.stageErr = os.MkdirAll(dir, 0755); r.stageErr != nil {
+			return
+		}
+		root, err := filepath.Abs(r.Root)
+		if err != nil {
+			r.stageErr = err
+			return
+		}
+		r.stageErr = os.WriteFile(filepath.Join(dir, stageRootFile), []byte(root+"\n"), 0644)
+		r.stage = stage
+	})
+	return r.stage, r.stageErr
+}
+
+// Stages returns the names of staging directories in trashDir, oldest first.
+func Stages(trashDir string) ([]string, error) {
+	entries, err := DefaultFS.ReadDir(trashDir)
+	if err != nil {
+		return nil, err
+	}
+	var stages []string
+	for _, entry := range entries {
+		if _, err := time.Parse(stageLayout, entry.Name()); err == nil && entry.IsDir() {
+			stages = append(stages, entry.Name())
+		}
+	}
+	sort.Strings(stages)
+	return stages, nil
+}
+
+// Restore moves all files of staging directory stage in trashDir back to where they were staged from, and removes the staging directory.
+// Files, whose original location is occupied again, are left in the staging directory and returned as conflicts;
+// the staging directory is then kept.
+func Restore(trashDir string, stage string) (conflicts []string, err error) {
+	dir := filepath.Join(trashDir, stage)
+	contents, err := os.ReadFile(filepath.Join(dir, stageRootFile))
+	if err != nil {
+		return nil, err
+	}
+	root := strings.TrimSuffix(string(contents), "\n")
+	// staged files are listed without Ignore and Search, so that every one of them is moved back before the directory is removed.
+	files, err := stagedFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range files {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return conflicts, err
+		}
+		dst := filepath.Join(root, rel)
+		if _, err := os.Lstat(dst); err == nil {
+			conflicts = append(conflicts, dst)
+			continue
+		}
+		if err := moveFile(path, dst); err != nil {
+			return conflicts, err
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflicts, nil
+	}
+	if left, err := stagedFiles(dir); err != nil {
+		return nil, err
+	} else if len(left) > 0 {
+		return nil, fmt.Errorf("walks: staging directory %v still contains %v after restore", dir, left[0])
+	}
+	if err := writable("remove", dir); err != nil {
+		return nil, err
+	}
+	return nil, os.RemoveAll(dir)
+}
+
+// stagedFiles returns the files (entries other than directories) in staging directory dir, except its stageRootFile.
+func stagedFiles(dir string) ([]string, error) {
+	var files []string
+	var list func(path string) error
+	list = func(path string) error {
+		entries, err := DefaultFS.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			name := filepath.Join(path, entry.Name())
+			if entry.IsDir() {
+				if err := list(name); err != nil {
+					return err
+				}
+			} else if path != dir || entry.Name() != stageRootFile {
+				files = append(files, name)
+			}
+		}
+		return nil
+	}
+	return files, list(dir)
+}
+
+// Restore moves staged files back like package's Restore does, if w grants CanWrite and CanDelete (see Require).
+func (w *Walker) Restore(trashDir string, stage string) (conflicts []string, err error) {
+	if err := w.Require(CanWrite|CanDelete, "restore", filepath.Join(trashDir, stage)); err != nil {
+		return nil, err
+	}
+	return Restore(trashDir, stage)
+}
+
+// Purge permanently deletes staging directories in trashDir staged longer than age ago, returning their names.
+func Purge(trashDir string, age time.Duration) ([]string, error) {
+	stages, err := Stages(trashDir)
+	if err != nil {
+		return nil, err
+	}
+	now := DefaultClock.Now()
+	var purged []string
+	for _, stage := range stages {
+		staged, _ := time.Parse(stageLayout, stage)
+		if now.Sub(staged) <= age {
+			continue
+		}
+		dir := filepath.Join(trashDir, stage)
+		if err := writable("remove", dir); err != nil {
+			return purged, err
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return purged, err
+		}
+		purged = append(purged, stage)
+	}
+	return purged, nil
+}
+
+// Purge deletes old staging directories like package's Purge does, if w grants CanDelete (see Require).
+func (w *Walker) Purge(trashDir string, age time.Duration) ([]string, error) {
+	if err := w.Require(CanDelete, "purge", trashDir); err != nil {
+		return nil, err
+	}
+	return Purge(trashDir, age)
+}
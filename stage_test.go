@@ -0,0 +1,71 @@
+package walks
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestRestoreIgnored(t *testing.T) {
+	root := t.TempDir()
+	trash := t.TempDir()
+	names := []string{"a.log", filepath.Join("sub", "b.txt"), filepath.Join("sub", ".hidden")}
+	r := &Remover{Mode: Stage, Root: root, TrashDir: trash}
+	for _, name := range names {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := r.Remove(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stage, err := r.Stage()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// files matching Ignore are restored too, before the staging directory is removed
+	defer func(ignore *regexp.Regexp) { Ignore = ignore }(Ignore)
+	Ignore = regexp.MustCompile(`\.log$|/\.`)
+	conflicts, err := Restore(trash, stage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conflicts) > 0 {
+		t.Fatalf("got conflicts %v, want none", conflicts)
+	}
+	for _, name := range names {
+		contents, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Errorf("%v not restored: %v", name, err)
+		} else if string(contents) != name {
+			t.Errorf("%v: got contents %q, want %q", name, contents, name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(trash, stage)); !os.IsNotExist(err) {
+		t.Errorf("staging directory not removed: %v", err)
+	}
+}
+
+func TestRemoverOutsideRoot(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "root")
+	outside := filepath.Join(dir, "outside")
+	if err := os.WriteFile(outside, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []RemoveMode{Trash, Stage} {
+		r := &Remover{Mode: mode, Root: root, TrashDir: filepath.Join(dir, "trash")}
+		if err := r.Remove(outside); err == nil {
+			t.Errorf("mode %v: removed %v outside of root", mode, outside)
+		}
+		if _, err := os.Stat(outside); err != nil {
+			t.Errorf("mode %v: %v", mode, err)
+		}
+	}
+}
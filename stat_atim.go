@@ -0,0 +1,22 @@
+//go:build aix || dragonfly || linux || openbsd || solaris
+// +build aix dragonfly linux openbsd solaris
+
+package walks
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and status change times of the file described by info, reporting whether they are available.
+func fileTimes(info os.FileInfo) (atime time.Time, ctime time.Time, ok bool) {
+	if sx, ok := info.Sys().(*Statx); ok {
+		return sx.Atime, sx.Ctime, !sx.Atime.IsZero()
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(st.Atim.Unix()), time.Unix(st.Ctim.Unix()), true
+}
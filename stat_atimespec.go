@@ -0,0 +1,19 @@
+//go:build darwin || freebsd || netbsd
+// +build darwin freebsd netbsd
+
+package walks
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// fileTimes returns the access and status change times of the file described by info, reporting whether they are available.
+func fileTimes(info os.FileInfo) (atime time.Time, ctime time.Time, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	return time.Unix(st.Atimespec.Unix()), time.Unix(st.Ctimespec.Unix()), true
+}
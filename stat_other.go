@@ -0,0 +1,29 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package walks
+
+import (
+	"os"
+	"time"
+)
+
+// fileID returns the device and inode numbers of the file described by info; they are not available on this platform.
+func fileID(info os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	return 0, 0, false
+}
+
+// fileTimes returns the access and status change times of the file described by info; they are not available on this platform.
+func fileTimes(info os.FileInfo) (atime time.Time, ctime time.Time, ok bool) {
+	return time.Time{}, time.Time{}, false
+}
+
+// fileOwner returns the user and group IDs of the owner of the file described by info; they are not available on this platform.
+func fileOwner(info os.FileInfo) (uid uint32, gid uint32, ok bool) {
+	return 0, 0, false
+}
+
+// fileLinks returns the number of hard links of the file described by info; it is not available on this platform.
+func fileLinks(info os.FileInfo) (uint64, bool) {
+	return 0, false
+}
@@ -0,0 +1,45 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package walks
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileID returns the device and inode numbers of the file described by info, reporting whether they are available.
+func fileID(info os.FileInfo) (dev uint64, ino uint64, ok bool) {
+	if sx, ok := info.Sys().(*Statx); ok {
+		return sx.Dev, sx.Ino, sx.Ino != 0
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint64(st.Dev), uint64(st.Ino), true
+}
+
+// fileOwner returns the user and group IDs of the owner of the file described by info, reporting whether they are available.
+func fileOwner(info os.FileInfo) (uid uint32, gid uint32, ok bool) {
+	if sx, ok := info.Sys().(*Statx); ok {
+		return sx.UID, sx.GID, sx.Mask&(statxUID|statxGID) != 0
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return uint32(st.Uid), uint32(st.Gid), true
+}
+
+// fileLinks returns the number of hard links of the file described by info, reporting whether it is available.
+func fileLinks(info os.FileInfo) (uint64, bool) {
+	if sx, ok := info.Sys().(*Statx); ok {
+		return uint64(sx.Nlink), sx.Nlink != 0
+	}
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return uint64(st.Nlink), true
+}
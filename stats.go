@@ -0,0 +1,58 @@
+package walks
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// walkCounts counts what one walk visited, for Walker's Stats. Counters are updated atomically, as the walk goes on concurrently.
+type walkCounts struct {
+	dirs, files, ignored, errors, bytes int64
+	maxDepth                            int64
+	clock                               Clock
+	start                               time.Time
+}
+
+// newWalkCounts returns counts of a walk starting now as told by clock.
+func newWalkCounts(clock Clock) *walkCounts {
+	return &walkCounts{clock: clock, start: clock.Now()}
+}
+
+// depth records, that contents of a directory at level were visited.
+func (c *walkCounts) depth(level int) {
+	for {
+		max := atomic.LoadInt64(&c.maxDepth)
+		if int64(level) <= max || atomic.CompareAndSwapInt64(&c.maxDepth, max, int64(level)) {
+			return
+		}
+	}
+}
+
+// stats returns the counts as Stats of a walk finishing now.
+func (c *walkCounts) stats() Stats {
+	return Stats{
+		MaxDepth: int(atomic.LoadInt64(&c.maxDepth)),
+		Dirs:     int(atomic.LoadInt64(&c.dirs)),
+		Files:    int(atomic.LoadInt64(&c.files)),
+		Ignored:  int(atomic.LoadInt64(&c.ignored)),
+		Errors:   int(atomic.LoadInt64(&c.errors)),
+		Bytes:    atomic.LoadInt64(&c.bytes),
+		Elapsed:  c.clock.Now().Sub(c.start),
+	}
+}
+
+// record keeps counts c of a finished walk as w's Stats.
+func (w *Walker) record(c *walkCounts) {
+	stats := c.stats()
+	w.usageMu.Lock()
+	w.stats = stats
+	w.usageMu.Unlock()
+}
+
+// Stats returns the statistics of the last finished walk of w (whether it succeeded, failed or was cancelled),
+// eg to report totals of a du-like tool, or to check how much Ignore and IgnoreFiles left out.
+func (w *Walker) Stats() Stats {
+	w.usageMu.Lock()
+	defer w.usageMu.Unlock()
+	return w.stats
+}
@@ -0,0 +1,124 @@
+package walks
+
+import (
+	"io/fs"
+	"time"
+)
+
+// StatFields selects the fields of entries' info read by Walker's FastStat; fields can be combined with |.
+// The entry's type is always read, and so are the identity fields of directories, which cycle detection needs.
+type StatFields uint
+
+const (
+	// StatMode reads permission bits.
+	StatMode StatFields = 1 << iota
+	// StatSize reads size and allocated blocks.
+	StatSize
+	// StatModTime reads modification time.
+	StatModTime
+	// StatOwner reads owner's user and group IDs.
+	StatOwner
+	// StatTimes reads access, status change and (where the filesystem records it) birth times.
+	StatTimes
+	// StatID reads device and inode numbers and the link count.
+	StatID
+	// StatAll reads all of the fields.
+	StatAll = StatMode | StatSize | StatModTime | StatOwner | StatTimes | StatID
+)
+
+// Statx is the system specific info (Sys of FileInfo) of entries read with Walker's FastStat.
+// Fields not requested, or not supported by the filesystem, are zero; Mask tells which ones were read (see statx(2)).
+type Statx struct {
+	Mask uint32
+	// Attributes are the STATX_ATTR_* flags of the entry (eg immutable, automount point), as far as the filesystem supports them.
+	Attributes uint64
+	Mode       uint16
+	Nlink      uint32
+	UID        uint32
+	GID        uint32
+	Ino        uint64
+	Size       int64
+	Blocks     int64
+	Dev        uint64
+	Rdev       uint64
+	Atime      time.Time
+	Mtime      time.Time
+	Ctime      time.Time
+	Btime      time.Time
+}
+
+// statx masks of Statx's fields, see statx(2).
+const (
+	statxType   = 0x1
+	statxMode   = 0x2
+	statxNlink  = 0x4
+	statxUID    = 0x8
+	statxGID    = 0x10
+	statxAtime  = 0x20
+	statxMtime  = 0x40
+	statxCtime  = 0x80
+	statxIno    = 0x100
+	statxSize   = 0x200
+	statxBlocks = 0x400
+	statxBtime  = 0x800
+)
+
+// statxInfo is the FileInfo of an entry read with statx.
+type statxInfo struct {
+	name string
+	st   Statx
+}
+
+// Name returns the base name of the entry.
+func (i *statxInfo) Name() string {
+	return i.name
+}
+
+// Size returns the size of the entry, 0 if not read.
+func (i *statxInfo) Size() int64 {
+	return i.st.Size
+}
+
+// Mode returns the type and permission bits of the entry.
+func (i *statxInfo) Mode() fs.FileMode {
+	m := fs.FileMode(i.st.Mode & 0777)
+	switch i.st.Mode & 0170000 {
+	case 0040000:
+		m |= fs.ModeDir
+	case 0120000:
+		m |= fs.ModeSymlink
+	case 0010000:
+		m |= fs.ModeNamedPipe
+	case 0140000:
+		m |= fs.ModeSocket
+	case 0060000:
+		m |= fs.ModeDevice
+	case 0020000:
+		m |= fs.ModeDevice | fs.ModeCharDevice
+	}
+	if i.st.Mode&04000 != 0 {
+		m |= fs.ModeSetuid
+	}
+	if i.st.Mode&02000 != 0 {
+		m |= fs.ModeSetgid
+	}
+	if i.st.Mode&01000 != 0 {
+		m |= fs.ModeSticky
+	}
+	return m
+}
+
+// ModTime returns the modification time of the entry, zero time if not read.
+func (i *statxInfo) ModTime() time.Time {
+	return i.st.Mtime
+}
+
+// IsDir reports whether the entry is a directory.
+func (i *statxInfo) IsDir() bool {
+	return i.Mode().IsDir()
+}
+
+// Sys returns the *Statx of the entry.
+func (i *statxInfo) Sys() interface{} {
+	return &i.st
+}
@@ -0,0 +1,201 @@
+package walks
+
+import (
+	"io/fs"
+	"runtime"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// sysStatx is the number of the statx system call (Linux 4.11+) on this architecture, 0 if not known.
+var sysStatx = map[string]uintptr{
+	"386":      383,
+	"amd64":    332,
+	"arm":      397,
+	"arm64":    291,
+	"loong64":  291,
+	"mips":     4366,
+	"mipsle":   4366,
+	"mips64":   5326,
+	"mips64le": 5326,
+	"ppc64":    383,
+	"ppc64le":  383,
+	"riscv64":  291,
+	"s390x":    379,
+}[runtime.GOARCH]
+
+// statx flags, see statx(2).
+const (
+	atFDCWD         = -100
+	atNoFollow      = 0x100
+	atNoAutomount   = 0x800
+	atStatxDontSync = 0x4000
+)
+
+// statxUnsupported is set when the kernel turns out not to have statx.
+var statxUnsupported int32
+
+// statxBuf is struct statx.
+type statxBuf struct {
+	Mask           uint32
+	Blksize        uint32
+	Attributes     uint64
+	Nlink          uint32
+	UID            uint32
+	GID            uint32
+	Mode           uint16
+	_              uint16
+	Ino            uint64
+	Size           uint64
+	Blocks         uint64
+	AttributesMask uint64
+	Atime          statxTimestamp
+	Btime          statxTimestamp
+	Ctime          statxTimestamp
+	Mtime          statxTimestamp
+	RdevMajor      uint32
+	RdevMinor      uint32
+	DevMajor       uint32
+	DevMinor       uint32
+	_              [14]uint64
+}
+
+type statxTimestamp struct {
+	Sec  int64
+	Nsec uint32
+	_    int32
+}
+
+// statxMask returns the statx mask of fields.
+func statxMask(fields StatFields) uint32 {
+	mask := uint32(statxType)
+	if fields&StatMode != 0 {
+		mask |= statxMode
+	}
+	if fields&StatSize != 0 {
+		mask |= statxSize | statxBlocks
+	}
+	if fields&StatModTime != 0 {
+		mask |= statxMtime
+	}
+	if fields&StatOwner != 0 {
+		mask |= statxUID | statxGID
+	}
+	if fields&StatTimes != 0 {
+		mask |= statxAtime | statxCtime | statxBtime
+	}
+	if fields&StatID != 0 {
+		mask |= statxIno | statxNlink
+	}
+	return mask
+}
+
+// statxEntry is a directory entry, whose info is read with statx, without following symbolic links nor triggering automounts.
+type statxEntry struct {
+	fs.DirEntry
+	path string
+	mask uint32
+}
+
+// Info returns the info of the entry, read with statx (or lstat on kernels without it).
+func (e statxEntry) Info() (fs.FileInfo, error) {
+	if atomic.LoadInt32(&statxUnsupported) != 0 {
+		return e.DirEntry.Info()
+	}
+	info, err := statx(e.path, e.Name(), e.mask)
+	if err == syscall.ENOSYS {
+		atomic.StoreInt32(&statxUnsupported, 1)
+		return e.DirEntry.Info()
+	} else if err != nil {
+		return nil, &fs.PathError{Op: "statx", Path: e.path, Err: err}
+	}
+	return info, nil
+}
+
+// statx returns the info of path named name, reading the fields in mask.
+func statx(path string, name string, mask uint32) (*statxInfo, error) {
+	if sysStatx == 0 {
+		return nil, syscall.ENOSYS
+	}
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf statxBuf
+	fd := atFDCWD
+	_, _, errno := syscall.Syscall6(sysStatx, uintptr(fd), uintptr(unsafe.Pointer(p)), atNoFollow|atNoAutomount|atStatxDontSync, uintptr(mask), uintptr(unsafe.Pointer(&buf)), 0)
+	if errno != 0 {
+		return nil, errno
+	}
+	st := Statx{
+		Mask:       buf.Mask,
+		Attributes: buf.Attributes & buf.AttributesMask,
+		Mode:       buf.Mode,
+		Nlink:      buf.Nlink,
+		UID:        buf.UID,
+		GID:        buf.GID,
+		Ino:        buf.Ino,
+		Size:       int64(buf.Size),
+		Blocks:     int64(buf.Blocks),
+		Dev:        mkdev(buf.DevMajor, buf.DevMinor),
+		Rdev:       mkdev(buf.RdevMajor, buf.RdevMinor),
+	}
+	if buf.Mask&statxAtime != 0 {
+		st.Atime = time.Unix(buf.Atime.Sec, int64(buf.Atime.Nsec))
+	}
+	if buf.Mask&statxMtime != 0 {
+		st.Mtime = time.Unix(buf.Mtime.Sec, int64(buf.Mtime.Nsec))
+	}
+	if buf.Mask&statxCtime != 0 {
+		st.Ctime = time.Unix(buf.Ctime.Sec, int64(buf.Ctime.Nsec))
+	}
+	if buf.Mask&statxBtime != 0 {
+		st.Btime = time.Unix(buf.Btime.Sec, int64(buf.Btime.Nsec))
+	}
+	return &statxInfo{name: name, st: st}, nil
+}
+
+// mkdev returns the device number of major and minor numbers, as encoded in st_dev by glibc.
+func mkdev(major uint32, minor uint32) uint64 {
+	return uint64(major&0xfffff000)<<32 | uint64(major&0xfff)<<8 | uint64(minor&0xffffff00)<<12 | uint64(minor&0xff)
+}
+
+// fastEntries wraps entries of directory dir to read their info with statx, when Walker's FastStat is set and the walk is on the operating system's filesystem.
+func (w *Walker) fastEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	if w.FastStat == 0 || sysStatx == 0 || atomic.LoadInt32(&statxUnsupported) != 0 {
+		return entries
+	}
+	if _, ok := w.fsys().(OSFS); !ok {
+		return entries
+	}
+	files, dirs := statxMask(w.FastStat), statxMask(w.FastStat|StatID)
+	for i, e := range entries {
+		if _, ok := e.(statxEntry); ok {
+			continue
+		}
+		mask := files
+		if e.IsDir() {
+			mask = dirs
+		}
+		entries[i] = statxEntry{DirEntry: e, path: joinPath(dir, e.Name()), mask: mask}
+	}
+	return entries
+}
+
+// birthTime returns the birth time of the file at path described by info: from info read with FastStat, or else read with statx.
+// It reports false, where the filesystem does not record it.
+func birthTime(path string, info fs.FileInfo) (time.Time, bool) {
+	if sx, ok := info.Sys().(*Statx); ok && sx.Mask&statxBtime != 0 {
+		return sx.Btime, true
+	}
+	if atomic.LoadInt32(&statxUnsupported) != 0 {
+		return time.Time{}, false
+	}
+	st, err := statx(path, info.Name(), statxBtime)
+	if err != nil || st.st.Mask&statxBtime == 0 {
+		return time.Time{}, false
+	}
+	return st.st.Btime, true
+}
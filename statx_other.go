@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package walks
+
+import (
+	"io/fs"
+	"time"
+)
+
+// fastEntries returns entries as they are, statx exists only on Linux.
+func (w *Walker) fastEntries(dir string, entries []fs.DirEntry) []fs.DirEntry {
+	return entries
+}
+
+// birthTime reports false, birth times are only read with statx on Linux.
+func birthTime(path string, info fs.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
@@ -0,0 +1,102 @@
+package walks
+
+import (
+	"sort"
+	"time"
+)
+
+// SubtreeFiles is the number of files found in one subtree by SuggestDepth.
+type SubtreeFiles struct {
+	Path  string
+	Files int64
+}
+
+// DepthSuggestion is the result of SuggestDepth.
+type DepthSuggestion struct {
+	// Depth is the smallest depth limit (as given to Walk), with which walks reach the requested share of files.
+	Depth int
+	// Subtrees are the fewest directories directly under the root (largest first), that together contain the requested share of files;
+	// files directly in the root are counted as the root's subtree.
+	Subtrees []SubtreeFiles
+	// Files is the number of regular files counted.
+	Files int64
+	// Complete is false if the budget ran out before the whole structure was counted; the suggestion then covers the levels counted so far.
+	Complete bool
+}
+
+// SuggestDepth counts files under root breadth-first within time budget and suggests how to limit or partition depth-limited walks:
+// the depth and the subtrees, that contain share (eg 0.99) of the files.
+// Being breadth-first, an exhausted budget leaves the deepest levels uncounted, not whole subtrees.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before SuggestDepth call.
+func SuggestDepth(root string, share float64, budget time.Duration) (DepthSuggestion, error) {
+	type queued struct {
+		dir     string
+		level   int
+		subtree string
+	}
+	deadline := DefaultClock.Now().Add(budget)
+	var perLevel []int64
+	perSubtree := make(map[string]int64)
+	var total int64
+	queue := []queued{{dir: root, level: 0}}
+	first := true // root is always listed
+	for len(queue) > 0 && (first || DefaultClock.Now().Before(deadline)) {
+		first = false
+		q := queue[0]
+		queue = queue[1:]
+		infos, err := DefaultFS.ReadDir(q.dir)
+		if err != nil && q.dir == root {
+			return DepthSuggestion{}, err
+		} else if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			pathName := joinPath(q.dir, info.Name())
+			if ignoredPath(pathName, info.IsDir()) {
+				continue
+			}
+			subtree := q.subtree
+			if q.level == 0 {
+				subtree = root
+				if info.IsDir() {
+					subtree = pathName
+				}
+			}
+			switch {
+			case info.IsDir():
+				queue = append(queue, queued{dir: pathName, level: q.level + 1, subtree: subtree})
+			case info.Type().IsRegular():
+				for len(perLevel) <= q.level {
+					perLevel = append(perLevel, 0)
+				}
+				perLevel[q.level]++
+				perSubtree[subtree]++
+				total++
+			}
+		}
+	}
+	sug := DepthSuggestion{Files: total, Complete: len(queue) == 0}
+	need := int64(share * float64(total))
+	var sum int64
+	for level, n := range perLevel {
+		sug.Depth = level
+		if sum += n; sum >= need {
+			break
+		}
+	}
+	for path, n := range perSubtree {
+		sug.Subtrees = append(sug.Subtrees, SubtreeFiles{Path: path, Files: n})
+	}
+	sort.Slice(sug.Subtrees, func(i, j int) bool {
+		a, b := sug.Subtrees[i], sug.Subtrees[j]
+		return a.Files > b.Files || a.Files == b.Files && a.Path < b.Path
+	})
+	sum = 0
+	for i, sub := range sug.Subtrees {
+		if sum += sub.Files; sum >= need {
+			sug.Subtrees = sug.Subtrees[:i+1]
+			break
+		}
+	}
+	return sug, nil
+}
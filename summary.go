@@ -0,0 +1,82 @@
+package walks
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// DirStats summarizes the contents of a walked directory, as passed to Walker's DirSummary callback.
+type DirStats struct {
+	Path  string
+	Depth int // level of the directory, -1 for the walked root
+
+	Files int   // regular files directly in the directory
+	Dirs  int   // subdirectories directly in the directory
+	Bytes int64 // total size of regular files directly in the directory
+
+	TotalFiles int   // regular files in the whole walked subtree
+	TotalDirs  int   // directories in the whole walked subtree, not counting the directory itself
+	TotalBytes int64 // total size of regular files in the whole walked subtree
+}
+
+// dirNode tracks a directory during the walk, until it and all of its walked subdirectories are processed.
+type dirNode struct {
+	parent  *dirNode
+	pending int32 // the listing of the directory itself and the subdirectories still being walked
+	mu      sync.Mutex
+	stats   DirStats
+
+	dev, ino uint64 // identity of the directory for cycle detection, if hasID
+	hasID    bool
+
+	f *os.File // the directory opened by its parent, in Walker's DirFD mode
+
+	ignore *ignoreLayer // rules of ignore files found in the directory and its ancestors
+	info   os.FileInfo  // info of the directory as listed in its parent, nil for the root
+
+	inSnapshot bool // the directory is a snapshot directory or inside one, see Walker's Snapshots
+
+	mounts *mountTable // mount points to apply Walker's Mounts to, shared by the whole walk
+}
+
+// newDirNode returns a node for directory path at given depth, whose listing is pending.
+func newDirNode(parent *dirNode, path string, depth int) *dirNode {
+	n := &dirNode{parent: parent, pending: 1, stats: DirStats{Path: path, Depth: depth}}
+	if parent != nil {
+		n.ignore, n.inSnapshot, n.mounts = parent.ignore, parent.inSnapshot, parent.mounts
+	}
+	return n
+}
+
+// spawn marks a subdirectory of n as pending.
+func (n *dirNode) spawn() {
+	atomic.AddInt32(&n.pending, 1)
+}
+
+// done marks one pending piece of n as processed.
+// When nothing is pending anymore, n's totals are completed, report is called with n and them (if report is not nil)
+// and totals are added to the parent, which is then marked done as well.
+func (n *dirNode) done(report func(*dirNode, DirStats)) {
+	if atomic.AddInt32(&n.pending, -1) != 0 {
+		return
+	}
+	n.mu.Lock()
+	n.stats.TotalFiles += n.stats.Files
+	n.stats.TotalDirs += n.stats.Dirs
+	n.stats.TotalBytes += n.stats.Bytes
+	stats := n.stats
+	n.mu.Unlock()
+	if report != nil {
+		report(n, stats)
+	}
+	if n.parent == nil {
+		return
+	}
+	n.parent.mu.Lock()
+	n.parent.stats.TotalFiles += stats.TotalFiles
+	n.parent.stats.TotalDirs += stats.TotalDirs
+	n.parent.stats.TotalBytes += stats.TotalBytes
+	n.parent.mu.Unlock()
+	n.parent.done(report)
+}
@@ -0,0 +1,39 @@
+package walks
+
+import (
+	"errors"
+	"os"
+)
+
+// SymlinkPolicy controls how Walker treats symbolic links met during a walk.
+type SymlinkPolicy uint
+
+const (
+	// SymlinkFail makes the walk fail on a symbolic link with an invalid path type error, as walks always did.
+	SymlinkFail SymlinkPolicy = iota
+	// SymlinkSkip skips symbolic links silently.
+	SymlinkSkip
+	// SymlinkReport passes symbolic links to Walker's SymlinkAction (skipping them if it is nil), without following them.
+	SymlinkReport
+	// SymlinkFollow follows symbolic links: a link to a directory is walked like a directory and a link to a file is passed to fileAction,
+	// both under the path of the link. Links forming a cycle are not descended into (see Guard and ErrCycle);
+	// broken links are passed to SymlinkAction (or skipped if it is nil).
+	SymlinkFollow
+)
+
+// symlink handles symbolic link e according to Walker's Symlinks policy.
+// It returns the info of the link's target, if the link is to be walked as its target, or nil if the walk is done with it.
+func (w *Walker) symlink(e Entry) (os.FileInfo, error) {
+	if w.Symlinks == SymlinkFollow {
+		target, err := w.stat(e.Path)
+		if err == nil && (target.IsDir() || target.Mode().IsRegular()) {
+			return target, nil
+		} else if errors.Is(err, ErrOpTimeout) {
+			return nil, err
+		}
+	}
+	if w.Symlinks == SymlinkSkip || w.SymlinkAction == nil || !w.included(e) {
+		return nil, nil
+	}
+	return nil, w.wrap(w.SymlinkAction)(e)
+}
@@ -0,0 +1,45 @@
+package walks
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+)
+
+// TreemapNode is a node of hierarchical size data, in the nested JSON shape consumed by treemap visualizers (eg d3-hierarchy).
+type TreemapNode struct {
+	Name string `json:"name"`
+	// Size is the total size of regular files at or under the node.
+	Size int64 `json:"size"`
+	// Files is the number of regular files at or under the node.
+	Files    int            `json:"files"`
+	Children []*TreemapNode `json:"children,omitempty"`
+}
+
+// Treemap returns the hierarchical size data of snap, rooted at its root.
+func Treemap(snap *Snapshot) *TreemapNode {
+	var build func(path string) *TreemapNode
+	build = func(path string) *TreemapNode {
+		entry := snap.Entries[path]
+		node := &TreemapNode{Name: filepath.Base(path)}
+		if path == snap.Root {
+			node.Name = path
+		}
+		if entry.Mode.IsRegular() {
+			node.Size, node.Files = entry.Size, 1
+		}
+		for _, child := range snap.Children(path) {
+			c := build(child.Path)
+			node.Size += c.Size
+			node.Files += c.Files
+			node.Children = append(node.Children, c)
+		}
+		return node
+	}
+	return build(snap.Root)
+}
+
+// WriteTreemap writes the hierarchical size data of snap to w as nested JSON (see TreemapNode).
+func WriteTreemap(w io.Writer, snap *Snapshot) error {
+	return json.NewEncoder(w).Encode(Treemap(snap))
+}
@@ -0,0 +1,182 @@
+package walks
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// vanishRecorder records paths reported as vanished, both by Walker's Vanished and by EventVanished.
+type vanishRecorder struct {
+	mu       sync.Mutex
+	vanished []string
+	events   []string
+}
+
+// watch makes r record the vanished paths of walks of w.
+func (r *vanishRecorder) watch(w *Walker) {
+	w.Vanished = func(path string) {
+		r.mu.Lock()
+		r.vanished = append(r.vanished, path)
+		r.mu.Unlock()
+	}
+	w.Subscribe(EventVanished, func(ev WalkEvent) {
+		r.mu.Lock()
+		r.events = append(r.events, ev.Path)
+		r.mu.Unlock()
+	})
+}
+
+// check fails t, unless exactly want were reported, each once.
+func (r *vanishRecorder) check(t *testing.T, want []string) {
+	t.Helper()
+	sort.Strings(r.vanished)
+	sort.Strings(r.events)
+	if !reflect.DeepEqual(r.vanished, want) {
+		t.Errorf("got vanished %v, want %v", r.vanished, want)
+	}
+	if !reflect.DeepEqual(r.events, want) {
+		t.Errorf("got vanished events %v, want %v", r.events, want)
+	}
+}
+
+func TestWalkerVanishedBeforeStat(t *testing.T) {
+	// c.txt and d2 are listed, but removed before their info is read
+	fsys := faultFS{FS: testTree(), infoErr: map[string]error{"mem/d1/c.txt": fs.ErrNotExist, "mem/d1/d2": fs.ErrNotExist}}
+	forModes(t, func(t *testing.T, ordered bool) {
+		w := New(WithFS(fsys), WithOrdered(ordered), WithCountBytes(true))
+		var r vanishRecorder
+		r.watch(w)
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+		want := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/skip", "mem/skip/g.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		r.check(t, testPaths("mem/d1/c.txt", "mem/d1/d2"))
+		if stats := w.Stats(); stats.Files != 3 || stats.Dirs != 2 || stats.Bytes != 9 || stats.Errors != 0 {
+			t.Errorf("got stats %+v, want 3 files of 9 bytes, 2 directories and no errors", stats)
+		}
+	})
+}
+
+func TestWalkerVanishedBeforeListing(t *testing.T) {
+	// d2 is statted and passed to dirAction, but removed before it is listed
+	fsys := faultFS{FS: testTree(), readDirErr: map[string]error{"mem/d1/d2": fs.ErrNotExist}}
+	forModes(t, func(t *testing.T, ordered bool) {
+		w := New(WithFS(fsys), WithOrdered(ordered))
+		var r vanishRecorder
+		r.watch(w)
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+		want := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/skip", "mem/skip/g.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		r.check(t, testPaths("mem/d1/d2"))
+		if stats := w.Stats(); stats.Files != 4 || stats.Dirs != 3 || stats.Errors != 0 {
+			t.Errorf("got stats %+v, want 4 files, 3 directories and no errors", stats)
+		}
+	})
+}
+
+func TestWalkerVanishedOS(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		root := t.TempDir()
+		var names []string
+		for i := 0; i < 20; i++ {
+			name := fmt.Sprintf("f%02d", i)
+			names = append(names, name)
+			if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// the first file's action removes the others, after the directory was listed
+		w := New(WithOrdered(ordered), WithCountBytes(true))
+		var r vanishRecorder
+		r.watch(w)
+		got, err := walkPaths(w, root, func(e Entry) error {
+			if e.Name() != names[0] {
+				return nil
+			}
+			for _, name := range names[1:] {
+				if err := os.Remove(filepath.Join(root, name)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+		if want := []string{filepath.Join(root, names[0])}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		var want []string
+		for _, name := range names[1:] {
+			want = append(want, filepath.Join(root, name))
+		}
+		r.check(t, want)
+		if stats := w.Stats(); stats.Files != 1 || stats.Errors != 0 {
+			t.Errorf("got stats %+v, want 1 file and no errors", stats)
+		}
+	})
+}
+
+func TestWalkerVanishedDirOS(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		root := t.TempDir()
+		sub := filepath.Join(root, "sub")
+		if err := os.MkdirAll(filepath.Join(sub, "deeper"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"a", "b", "deeper/c"} {
+			if err := os.WriteFile(filepath.Join(sub, name), nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// dirAction of sub removes it, after it was statted; in Ordered mode its listing may have been read ahead already
+		w := New(WithOrdered(ordered), WithCountBytes(true))
+		var r vanishRecorder
+		r.watch(w)
+		got, err := walkPaths(w, root, func(e Entry) error {
+			if e.Path == sub {
+				return os.RemoveAll(sub)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+		if want := []string{sub}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		// a listing read ahead while sub was being removed may miss its entries, so that nothing vanishes
+		if len(r.vanished) == 0 && !ordered {
+			t.Error("no vanished paths reported")
+		}
+		seen := make(map[string]bool)
+		for _, path := range r.vanished {
+			if path != sub && !strings.HasPrefix(path, sub+string(filepath.Separator)) {
+				t.Errorf("vanished %v is not in removed %v", path, sub)
+			}
+			if seen[path] {
+				t.Errorf("vanished %v reported twice", path)
+			}
+			seen[path] = true
+		}
+		if stats := w.Stats(); stats.Dirs != 1 || stats.Files != 0 || stats.Errors != 0 {
+			t.Errorf("got stats %+v, want 1 directory, no files and no errors", stats)
+		}
+	})
+}
@@ -0,0 +1,119 @@
+package walks
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// ErrShadowCopyUnsupported is returned by shadow copy functions on platforms without Volume Shadow Copy Service (anything but Windows).
+var ErrShadowCopyUnsupported = errors.New("walks: shadow copies are not supported on this platform")
+
+// ShadowCopy is a Volume Shadow Copy: a consistent, read-only point in time view of a Windows volume.
+// Files that are locked or being written on the live volume can be read from the shadow copy as they were when it was created.
+type ShadowCopy struct {
+	// ID is the shadow copy's identifier, eg "{42B0C4E2-...}".
+	ID string
+	// Volume is the live volume the copy was taken of, eg `C:\`.
+	Volume string
+	// Device is the device path of the copy, eg `\\?\GLOBALROOT\Device\HarddiskVolumeShadowCopy3`.
+	Device string
+
+	created bool // copy was created by CreateShadowCopy and is deleted on Close
+}
+
+// Path returns the path in the shadow copy corresponding to path on the live volume.
+func (s *ShadowCopy) Path(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, ok := cutVolume(abs, s.Volume)
+	if !ok {
+		return "", &ShadowPathError{Path: path, Volume: s.Volume}
+	}
+	return s.Device + `\` + rel, nil
+}
+
+// Live returns the path on the live volume corresponding to path in the shadow copy;
+// paths outside the shadow copy are returned as they are.
+func (s *ShadowCopy) Live(path string) string {
+	rel, ok := cutVolume(path, s.Device)
+	if !ok {
+		return path
+	}
+	return filepath.Join(s.Volume, rel)
+}
+
+// Close deletes the shadow copy if it was created by CreateShadowCopy; copies attached with OpenShadowCopy are left in place.
+func (s *ShadowCopy) Close() error {
+	if !s.created {
+		return nil
+	}
+	s.created = false
+	return deleteShadowCopy(s.ID)
+}
+
+// ShadowPathError is returned by ShadowCopy's Path for paths that are not on the shadowed volume.
+type ShadowPathError struct {
+	Path   string
+	Volume string
+}
+
+func (e *ShadowPathError) Error() string {
+	return "walks: " + e.Path + " is not on volume " + e.Volume
+}
+
+// cutVolume returns path relative to volume (compared case-insensitively, like Windows does) and whether path is on it.
+func cutVolume(path string, volume string) (string, bool) {
+	volume = strings.TrimRight(volume, `\/`)
+	if len(path) < len(volume) || !strings.EqualFold(path[:len(volume)], volume) {
+		return "", false
+	}
+	rest := path[len(volume):]
+	if rest != "" && rest[0] != '\\' && rest[0] != '/' {
+		return "", false
+	}
+	return strings.TrimLeft(rest, `\/`), true
+}
+
+// CreateShadowCopy creates a new shadow copy of the volume containing path (Windows only, requires administrator rights).
+// The copy must be closed with Close, which deletes it.
+func CreateShadowCopy(path string) (*ShadowCopy, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	volume := filepath.VolumeName(abs) + `\`
+	s, err := createShadowCopy(volume)
+	if err != nil {
+		return nil, err
+	}
+	s.created = true
+	return s, nil
+}
+
+// OpenShadowCopy attaches to the existing shadow copy with identifier id (Windows only).
+func OpenShadowCopy(id string) (*ShadowCopy, error) {
+	return openShadowCopy(id)
+}
+
+// WalkShadowCopy walks concurrently given directory structure with Walker's configuration in a new shadow copy of its volume,
+// so that the walk sees a stable view of files, that are locked or changing on the live volume (eg for backups).
+// Actions are given paths in the shadow copy, to be opened there; Live converts them back to paths on the live volume.
+// The shadow copy is deleted when the walk is done.
+func (w *Walker) WalkShadowCopy(ctx context.Context, root string, fileAction func(*ShadowCopy, Entry) error, dirAction func(*ShadowCopy, Entry) error) error {
+	s, err := CreateShadowCopy(root)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	shadowRoot, err := s.Path(root)
+	if err != nil {
+		return err
+	}
+	return w.WalkEntries(ctx, shadowRoot,
+		func(e Entry) error { return fileAction(s, e) },
+		func(e Entry) error { return dirAction(s, e) })
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+// +build !windows
+
+package walks
+
+// createShadowCopy returns ErrShadowCopyUnsupported, shadow copies exist only on Windows.
+func createShadowCopy(volume string) (*ShadowCopy, error) {
+	return nil, ErrShadowCopyUnsupported
+}
+
+// openShadowCopy returns ErrShadowCopyUnsupported, shadow copies exist only on Windows.
+func openShadowCopy(id string) (*ShadowCopy, error) {
+	return nil, ErrShadowCopyUnsupported
+}
+
+// deleteShadowCopy returns ErrShadowCopyUnsupported, shadow copies exist only on Windows.
+func deleteShadowCopy(id string) error {
+	return ErrShadowCopyUnsupported
+}
@@ -0,0 +1,67 @@
+//go:build windows
+// +build windows
+
+package walks
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Shadow copies are managed through WMI class Win32_ShadowCopy with PowerShell, which ships with every supported Windows,
+// rather than through the COM interfaces of VSS.
+
+// createShadowCopy creates a client accessible shadow copy of volume.
+func createShadowCopy(volume string) (*ShadowCopy, error) {
+	script := fmt.Sprintf(`$r = Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume=%v; Context='ClientAccessible'}
+if ($r.ReturnValue -ne 0) { [Console]::Error.WriteLine("Win32_ShadowCopy.Create returned $($r.ReturnValue)"); exit 1 }
+$r.ShadowID`, psQuote(volume))
+	out, err := powershell(script)
+	if err != nil {
+		return nil, err
+	}
+	return openShadowCopy(strings.TrimSpace(out))
+}
+
+// openShadowCopy looks up shadow copy id.
+func openShadowCopy(id string) (*ShadowCopy, error) {
+	script := fmt.Sprintf(`$s = Get-CimInstance Win32_ShadowCopy -Filter ("ID=" + %v)
+if (-not $s) { [Console]::Error.WriteLine("no such shadow copy"); exit 1 }
+$v = Get-CimInstance Win32_Volume -Filter ("DeviceID='" + $s.VolumeName.Replace('\', '\\') + "'")
+$s.DeviceObject
+$v.Name`, psQuote("'"+id+"'"))
+	out, err := powershell(script)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(strings.Replace(out, "\r\n", "\n", -1)), "\n")
+	if len(lines) != 2 {
+		return nil, fmt.Errorf("walks: shadow copy %v: unexpected output %q", id, out)
+	}
+	return &ShadowCopy{ID: id, Device: lines[0], Volume: lines[1]}, nil
+}
+
+// deleteShadowCopy deletes shadow copy id.
+func deleteShadowCopy(id string) error {
+	_, err := powershell(fmt.Sprintf(`Get-CimInstance Win32_ShadowCopy -Filter ("ID=" + %v) | Remove-CimInstance`, psQuote("'"+id+"'")))
+	return err
+}
+
+// powershell runs script and returns its standard output.
+func powershell(script string) (string, error) {
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", script)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("walks: shadow copy: %v: %v", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// psQuote quotes s as PowerShell single quoted string.
+func psQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,40 @@
+package walks
+
+import "context"
+
+// WalkChan walks concurrently given directory structure with Walker's configuration, streaming every visited file and directory
+// over the returned entry channel instead of calling actions, so consumers can select on it or feed it into their own pipelines.
+// The entry channel is closed when the walk is done; the error channel then receives the walk's error (nil on success) and is closed.
+// To stop early, call the returned stop function (or cancel ctx): the walk stops and the channels are closed, even if the entry channel is abandoned.
+// The entry channel must otherwise be drained, as the walk waits for each entry to be received; stop may be called any number of times,
+// and calling it once the entries are consumed, eg with defer, releases the walk's resources.
+// Entries are cloned (see Entry.Clone), so they can be kept.
+func (w *Walker) WalkChan(ctx context.Context, root string) (<-chan Entry, <-chan error, func()) {
+	ctx, stop := context.WithCancel(ctx)
+	entries := make(chan Entry)
+	errc := make(chan error, 1)
+	send := func(e Entry) error {
+		select {
+		case entries <- e.Clone():
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	go func() {
+		defer close(errc)
+		err := w.WalkEntries(ctx, root, send, send)
+		close(entries)
+		errc <- err
+	}()
+	return entries, errc, stop
+}
+
+// WalkChan walks concurrently given directory structure like Walker's WalkChan does, with a Walker configured with opts
+// (using package's Ignore and Search, unless they set their own).
+// There is no stop function: the entry channel must be drained until it is closed, or the walk is blocked forever;
+// walks that may be stopped early are done with Walker's WalkChan.
+func WalkChan(root string, opts ...Option) (<-chan Entry, <-chan error) {
+	entries, errc, _ := New(opts...).WalkChan(context.Background(), root)
+	return entries, errc
+}
@@ -0,0 +1,49 @@
+package walks
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWalkChan(t *testing.T) {
+	entries, errc := WalkChan("mem", WithFS(testTree()))
+	var got []string
+	for e := range entries {
+		got = append(got, e.Path)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, allTestPaths) {
+		t.Errorf("got %v, want %v", got, allTestPaths)
+	}
+}
+
+func TestWalkChanStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 10; i++ {
+		// the entry channel is abandoned after its first entry
+		w := New(WithFS(testTree()))
+		entries, errc, stop := w.WalkChan(context.Background(), "mem")
+		if _, ok := <-entries; !ok {
+			t.Fatal("no entries")
+		}
+		stop()
+		if err := <-errc; err != context.Canceled {
+			t.Errorf("got error %v, want %v", err, context.Canceled)
+		}
+	}
+	// goroutines of the stopped walks may still be finishing
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("got %v goroutines after the walks were stopped, want at most %v", n, before)
+	}
+}
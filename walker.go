@@ -0,0 +1,855 @@
+package walks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTempQuota is returned when writing to a temporary file would exceed Walker's TempQuota.
+var ErrTempQuota = errors.New("walks: temporary workspace quota exceeded")
+
+// RootPolicy controls how Walker treats walked root that does not exist or is not a directory.
+// Zero value makes the walk return an error in both cases; policies can be combined with |.
+type RootPolicy uint
+
+const (
+	// RootFileAsEntry treats root, that is a regular file, as the only entry of the walk, calling fileAction on it.
+	// This is convenient when roots come from expanded globs.
+	RootFileAsEntry RootPolicy = 1 << iota
+	// RootCreateMissing creates missing root directory (with its parents) and walks the resulting empty directory.
+	RootCreateMissing
+)
+
+// Walker holds configuration of a walk and the resources tied to it, such as the temporary workspace for actions.
+// Each Walker carries its own settings and tracks its own goroutines, so walks with different configurations can run concurrently;
+// package functions like Walk are thin wrappers of a Walker using package-level Ignore and Search.
+type Walker struct {
+	// Depth controls the depth of walked directory structure, -1 means no limit (same as Walk's depth).
+	Depth int
+	// Ignore holds regexp expression of directories and files to ignore; nil means package's Ignore.
+	Ignore *regexp.Regexp
+	// IgnoreRules holds gitignore rules of directories and files to ignore. When Ignore or IgnoreRules is set, package's Ignore and IgnoreRules are not used.
+	IgnoreRules *Gitignore
+	// IgnoreFormat is the format of ignore files read by SetIgnore.
+	IgnoreFormat IgnoreFormat
+	// IgnoreFiles are the names of ignore files (eg ".walkignore" or ".gitignore") applied as the walk descends, like git and ripgrep do:
+	// each one found in a walked directory is read with gitignore semantics, relative to that directory, and applies to everything under it,
+	// with the rules of deeper files taking precedence. They add to Ignore and IgnoreRules, and cannot re-include what those exclude.
+	IgnoreFiles []string
+	// Search holds expressions of directories and files to search; nil means package's Search.
+	// Only directories and files matching it are passed to the actions; directories not matching are still descended into,
+	// so that matching entries under them are found. Ignore takes precedence: ignored entries are neither passed to actions nor descended into.
+	Search *regexp.Regexp
+	// Filter, if not nil, is called for each directory and file matching Search before the actions, which it is passed to only if Filter returns true;
+	// it can select by anything known of an entry (size, modification time, mode bits, ...), not just its path.
+	// Like Search, it does not stop the walk from descending into directories (see SkipDir for that).
+	Filter func(path string, d fs.DirEntry) bool
+	// Root controls what happens when walked root does not exist or is not a directory.
+	Root RootPolicy
+	// DirSummary, if not nil, is called after all contents of a walked directory (including its walked subdirectories) are processed,
+	// with the counts and sizes of the directory's contents. It is also called for the root, and it is not called when the walk fails or is cancelled.
+	// Like the actions, it may be called concurrently.
+	DirSummary func(DirStats)
+	// DirActionPost, if not nil, is called in post-order for each directory walked: after all of its contents (including its walked subdirectories)
+	// are processed, eg to remove emptied directories or to aggregate sizes bottom-up. It is not called for the root, nor for directories
+	// not descended into (because of depth, SkipDir or Guard), nor when the walk fails or is cancelled.
+	// Errors are treated like errors of dirAction (SkipDir is ignored); like the actions, it may be called concurrently.
+	DirActionPost func(Entry) error
+	// DepthCeiling is an absolute limit of the depth of walked directories, protecting against pathologically deep trees; 0 means no limit.
+	// Unlike Depth, reaching it is reported to Guard.
+	DepthCeiling int
+	// Guard, if not nil, is called for each directory that is not descended into, because it would form a cycle (ErrCycle)
+	// or exceed DepthCeiling (ErrDepthCeiling). The directory itself is still passed to dirAction.
+	Guard func(path string, err error)
+	// Vanished, if not nil, is called for each entry removed during the walk (eg in caches and spools): listed in its directory,
+	// but gone before it could be examined, or a subdirectory gone before it could be listed. Such entries are skipped either way.
+	Vanished func(path string)
+	// DirFD makes the walk hold each directory open while it is listed, and open its subdirectories relative to it (with openat where supported),
+	// instead of resolving their full paths. The walk is then robust against concurrent renames of ancestor directories and cheaper on deep trees.
+	// DirFD mode always walks the operating system's filesystem, ignoring FS; up to one file descriptor is held by each directory waiting to be walked.
+	DirFD bool
+	// Capabilities restrict what built-in actions tied to the Walker may do (see Require); its own temporary workspace and
+	// RootCreateMissing need CanWrite. Zero value grants all capabilities, NoCapabilities none.
+	Capabilities Capability
+	// MaxWorkers, if positive, makes the walk use a fixed pool of MaxWorkers goroutines taking directories from a work queue,
+	// instead of one goroutine per directory, which keeps deep or wide trees from exploding the number of goroutines.
+	// The root is listed by the pool too, so that at most MaxWorkers directories are listed at the same time.
+	// Nested walks sharing the pool of the enclosing walk (see NestedShared) do not start another pool.
+	// Quota's MaxWorkers limits the directories listed at the same time independently: with both set, the smaller one holds,
+	// but only Walker's MaxWorkers bounds the number of goroutines.
+	MaxWorkers int
+	// Boundary, if not nil, is called for each directory, where the walk enters another filesystem, Btrfs subvolume or ZFS dataset (see Boundary).
+	// The directory is walked as usual; like the actions, Boundary may be called concurrently.
+	Boundary func(Boundary)
+	// Snapshots controls how filesystem snapshot directories are walked (see IsSnapshotDir); zero value walks them like other directories.
+	Snapshots SnapshotPolicy
+	// Ordered makes the walk deterministic: directory listings are still read concurrently (by up to MaxWorkers goroutines, or one per CPU),
+	// but the actions are called one at a time, in depth-first order with each directory's entries sorted by name (like Iterator does),
+	// so that listings, checksums and archives are reproducible; DirSummary and EventDirDone come in post-order. Ordered walks do not apply DirFD, Quota and Nested.
+	Ordered bool
+	// Quota limits the resources used by each walk, see Usage for the resources actually used.
+	Quota Quota
+	// Nested controls walks started with the Walker while another of its walks is running, eg from an action descending into an archive.
+	Nested NestedPolicy
+	// Symlinks controls how symbolic links are treated; zero value makes them fail the walk.
+	Symlinks SymlinkPolicy
+	// SymlinkAction is called with symbolic links not followed under SymlinkReport and SymlinkFollow policies, that match Search and Filter.
+	// Its errors are treated like errors of fileAction.
+	SymlinkAction func(Entry) error
+	// OtherAction, if not nil, is called for special files: devices, named pipes, sockets and other entries, that are neither directories,
+	// regular files nor symbolic links (these are handled by Symlinks); Entry's Type tells which kind it is. It is subject to Search and Filter like the actions,
+	// and its errors are treated like errors of fileAction. When nil, special files fail the walk with an invalid path type error (see Errors).
+	OtherAction func(Entry) error
+	// Errors controls how errors of the walk itself (not of the actions) are treated, see ErrorPolicy; zero value stops the walk on the first one.
+	Errors ErrorPolicy
+	// ErrorAction, if not nil, is called with each error of the walk itself and the path it occurred at, before Errors policy applies.
+	// Returning nil skips the failed entry regardless of the policy, SkipAll stops the walk without error,
+	// and any other error (eg err itself) is handled by the policy. Like the actions, it may be called concurrently.
+	ErrorAction func(path string, err error) error
+	// FastStat, if not zero, makes the walk read entries' info on Linux with statx(2), requesting only the given fields
+	// (those its Filter and actions need, plus the identity of directories), without triggering automounts
+	// (eg of /home or /net entries) and without syncing attributes of network filesystems. Fields not requested read as zero;
+	// entries' Sys is then *Statx. It is ignored on other platforms, for FS other than OSFS, and in DirFD mode.
+	FastStat StatFields
+	// LongPaths makes the walk start from LongPath of root, so that deep trees on Windows are not limited by MAX_PATH;
+	// paths passed to the actions then start with `\\?\` (or `\\?\UNC\`). It changes nothing on other platforms, but makes the paths absolute.
+	LongPaths bool
+	// Mounts controls how automount points and network filesystems in the walked tree are treated, see MountPolicy; zero value walks them.
+	Mounts MountPolicy
+	// MountTimeout is the time automount points and network filesystems must answer a probe within under MountsTimeout and MountsFail;
+	// 0 means DefaultMountTimeout.
+	MountTimeout time.Duration
+	// OpTimeout, if positive, limits the time each filesystem operation of the walk (opening, statting and listing a directory, statting an entry)
+	// may take: operations are run by a small pool of goroutines, and one not completing in time fails with ErrOpTimeout, handled by Errors policy,
+	// so that a dead network mount does not hang the whole walk. The operation itself cannot be cancelled; it is reported to Hung and by HungPaths until it returns.
+	// Handing each operation over to the pool makes walks of healthy trees slower, up to about twice.
+	OpTimeout time.Duration
+	// Hung, if not nil, is called for each filesystem operation, that timed out (see OpTimeout). Like the actions, it may be called concurrently.
+	Hung func(HungPath)
+	// Cache, if not nil, caches directory listings between walks, reusing them for directories that have not changed (see DirCache for the caveats).
+	// It is not used in DirFD mode.
+	Cache *DirCache
+	// CountBytes makes the walk read the size of each regular file, for Stats' Bytes; sizes are read anyway, when DirSummary is set.
+	CountBytes bool
+	// FS is the filesystem to walk; nil means DefaultFS.
+	FS FS
+	// Clock tells the time of the walk's Stats, Usage and Quota, and of the staging directories of the Walker's Remover; nil means DefaultClock.
+	Clock Clock
+	// TempQuota limits the total number of bytes written to files created with TempFile, 0 means no limit.
+	TempQuota int64
+	// RetainEntries makes the walk pass cloned entries (see Entry.Clone) to all of its actions, so that they can be kept after the actions return.
+	RetainEntries bool
+
+	optErr     error // first error of the options given to New
+	bus        bus
+	middleware []Middleware
+
+	usageMu sync.Mutex
+	usage   QuotaUsage
+	stats   Stats // of the last finished walk
+
+	lifeMu sync.Mutex
+	active int           // walks in progress
+	done   chan struct{} // closed when active drops to 0
+	pool   *limiter      // limiter of the outermost running walk, shared with nested walks
+	ops    *opPool       // pool running filesystem operations under OpTimeout, while walks are running
+
+	hungMu sync.Mutex
+	hung   map[*opTask]bool // timed out operations, that have not returned, with whether their worker was replaced
+
+	tempMu   sync.Mutex
+	tempDir  string
+	tempUsed int64
+}
+
+// New returns a Walker with no depth limit, configured with opts.
+// The first error of opts is kept and returned by every walk of the Walker.
+func New(opts ...Option) *Walker {
+	w := &Walker{Depth: -1}
+	for _, opt := range opts {
+		if err := opt(w); err != nil && w.optErr == nil {
+			w.optErr = err
+		}
+	}
+	return w
+}
+
+// clock returns Walker's Clock, defaulting to DefaultClock (also for a nil Walker).
+func (w *Walker) clock() Clock {
+	if w == nil || w.Clock == nil {
+		return DefaultClock
+	}
+	return w.Clock
+}
+
+// fsys returns Walker's FS, defaulting to DefaultFS.
+func (w *Walker) fsys() FS {
+	if w.FS == nil {
+		return DefaultFS
+	}
+	return w.FS
+}
+
+// Walk walks concurrently given directory structure like Walk does, using Walker's configuration.
+// Temporary workspace created during the walk (see TempDir) is removed when the walk is done.
+func (w *Walker) Walk(root string, fileAction func(string), dirAction func(string)) {
+	defer w.cleanTemp()
+	if err := w.walk(context.Background(), root, plainAction(fileAction), plainAction(dirAction)); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// WalkContext walks concurrently given directory structure like WalkContext does, using Walker's configuration.
+// When ctx is done, no more goroutines are spawned nor actions invoked, and ctx.Err() is returned as soon as the running actions return.
+// Temporary workspace created during the walk (see TempDir) is removed when the walk is done.
+func (w *Walker) WalkContext(ctx context.Context, root string, fileAction func(string), dirAction func(string)) error {
+	defer w.cleanTemp()
+	return w.walk(ctx, root, plainAction(fileAction), plainAction(dirAction))
+}
+
+// WalkErr walks concurrently given directory structure like WalkContext does, with actions that can fail or direct the walk, like in filepath.WalkDir:
+// SkipDir returned by dirAction skips the contents of the directory, SkipDir returned by fileAction skips the remaining entries of its directory,
+// and SkipAll stops the whole walk, returning nil. Any other error stops the walk, and is returned.
+// Temporary workspace created during the walk (see TempDir) is removed when the walk is done.
+func (w *Walker) WalkErr(ctx context.Context, root string, fileAction func(string) error, dirAction func(string) error) error {
+	defer w.cleanTemp()
+	return w.walk(ctx, root, errAction(fileAction), errAction(dirAction))
+}
+
+// WalkEntries is WalkErr with actions receiving the walked Entry instead of its path,
+// so that they can use the entry's type and info (see Entry.Info), that the walk already has, without statting the entry again.
+// Entries passed to the actions must not be kept after the actions return, unless copied with Entry.Clone.
+func (w *Walker) WalkEntries(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error) error {
+	defer w.cleanTemp()
+	return w.walk(ctx, root, fileAction, dirAction)
+}
+
+// walk is Walker's inner function, that walks the directory structure concurrently until it is done, an error occurs or ctx is cancelled.
+// First error stops spawning new goroutines and invoking actions, and is returned once all started goroutines have finished.
+// Errors of the walk itself are first given to Walker's ErrorAction and Errors policy, see pathError.
+// Actions' errors are handled as documented by WalkErr.
+func (w *Walker) walk(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error) error {
+	return w.run(ctx, root, fileAction, dirAction, w.Ordered)
+}
+
+// run is walk, that walks in Ordered mode if ordered.
+func (w *Walker) run(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error, ordered bool) error {
+	counts := newWalkCounts(w.clock())
+	defer w.record(counts)
+	return w.runCounted(ctx, root, fileAction, dirAction, ordered, counts)
+}
+
+// runCounted is run, that counts the walk in counts, without recording them as Walker's Stats.
+func (w *Walker) runCounted(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error, ordered bool, counts *walkCounts) error {
+	if w.optErr != nil {
+		return w.optErr
+	}
+	if w.LongPaths {
+		var err error
+		if root, err = LongPath(root); err != nil {
+			return err
+		}
+	}
+	w.start()
+	defer w.finish()
+	fileAction, dirAction = w.wrap(fileAction), w.wrap(dirAction)
+	if isDir, err := w.checkRoot(root); err != nil {
+		return err
+	} else if !isDir {
+		info, _ := w.stat(root)
+		return w.walkFile(root, info, fileAction, counts)
+	}
+	if ordered {
+		return w.walkOrdered(ctx, root, fileAction, dirAction, counts)
+	}
+	walkCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lim, shared := w.limiter()
+	defer w.release(lim, shared)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	sink := errorSink{counts: counts}
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+			if err != SkipAll {
+				w.bus.publish(WalkEvent{Kind: EventError, Err: err})
+			}
+		})
+	}
+	report := func(n *dirNode, stats DirStats) {
+		if walkCtx.Err() != nil {
+			return
+		}
+		w.bus.publish(WalkEvent{Kind: EventDirDone, Path: stats.Path, Stats: &stats})
+		if w.DirSummary != nil {
+			w.DirSummary(stats)
+		}
+		if w.DirActionPost == nil || n.parent == nil {
+			return
+		}
+		if e := (Entry{Path: stats.Path, Depth: stats.Depth, info: n.info}); w.acts(n.inSnapshot) && w.included(e) {
+			if err := w.wrap(w.DirActionPost)(e); err != nil && err != SkipDir {
+				fail(err)
+			}
+		}
+	}
+	// sizes of files are only read, when directory stats are reported
+	sizes := w.CountBytes || w.DirSummary != nil || w.bus.wants(EventDirDone)
+	var queue *workQueue
+	if w.MaxWorkers > 0 && !shared {
+		queue = newWorkQueue(w.MaxWorkers)
+		defer queue.close()
+	}
+	var walkDir func(dir string, level int, node *dirNode)
+	walkDir = func(dir string, level int, node *dirNode) {
+		defer wg.Done()
+		defer node.done(report)
+		if !shared {
+			release, err := lim.acquire(walkCtx)
+			if err != nil {
+				// the directory opened by the parent in DirFD mode is not read
+				if node.f != nil {
+					node.f.Close()
+				}
+				fail(err)
+				return
+			}
+			defer release()
+		}
+		var dirFile *os.File
+		var subpaths []fs.DirEntry
+		var err error
+		if w.DirFD {
+			if dirFile, err = w.openDir(node, dir); err == nil {
+				defer dirFile.Close()
+				if subpaths, err = w.readDirFile(dir, dirFile); err == nil {
+					subpaths = w.timedEntries(dir, subpaths)
+				}
+			}
+		} else {
+			if subpaths, err = w.readDir(dir); err == nil {
+				subpaths = w.timedEntries(dir, w.fastEntries(dir, subpaths))
+			}
+		}
+		if os.IsNotExist(err) && node.parent != nil {
+			w.vanished(dir)
+			return
+		}
+		if err == nil && len(w.IgnoreFiles) > 0 {
+			node.ignore, err = w.loadIgnoreFiles(dir, subpaths, node.ignore)
+		}
+		if err != nil {
+			if err := w.pathError(&sink, dir, err); err != nil {
+				fail(err)
+			}
+			return
+		}
+		counts.depth(level)
+		for _, path := range subpaths {
+			if walkCtx.Err() != nil {
+				return
+			}
+			c, err := w.classify(walkCtx, lim, &sink, node, level, path, sizes)
+			if err != nil {
+				fail(err)
+				return
+			}
+			switch c.class {
+			case classSkipRest:
+				return
+			case classDir:
+				pathName := c.entry.Path
+				if c.acts {
+					if err := dirAction(c.entry); err == SkipDir {
+						continue
+					} else if err != nil {
+						fail(err)
+						return
+					}
+				}
+				if w.Depth != -1 && level >= w.Depth || walkCtx.Err() != nil {
+					continue
+				}
+				child := newDirNode(node, pathName, level)
+				child.setID(c.entry.info)
+				w.boundary(node, child)
+				child.info, child.inSnapshot = c.entry.info, c.inSnapshot
+				if !w.guard(child, level+1) {
+					continue
+				}
+				if dirFile != nil && !c.followed {
+					f, err := w.openDirAt(dirFile, pathName, path.Name())
+					if os.IsNotExist(err) {
+						w.vanished(pathName)
+						continue
+					} else if err != nil {
+						if err := w.pathError(&sink, pathName, err); err != nil {
+							fail(err)
+							return
+						}
+						continue
+					}
+					child.f = f
+				}
+				wg.Add(1)
+				node.spawn()
+				switch {
+				case shared:
+					walkDir(pathName, level+1, child)
+				case queue != nil:
+					queue.push(func() { walkDir(pathName, level+1, child) })
+				default:
+					go walkDir(pathName, level+1, child)
+				}
+			case classFile, classOther:
+				action := fileAction
+				if c.class == classOther {
+					action = w.wrap(w.OtherAction)
+				}
+				if c.acts {
+					if err := action(c.entry); err == SkipDir {
+						return
+					} else if err != nil {
+						fail(err)
+						return
+					}
+				}
+			}
+		}
+	}
+	rootNode := newDirNode(nil, root, -1)
+	if info, err := w.stat(root); err == nil {
+		rootNode.setID(info)
+	}
+	rootNode.mounts = w.newMountTable(root)
+	wg.Add(1)
+	if queue != nil {
+		queue.push(func() { walkDir(root, 0, rootNode) })
+	} else {
+		walkDir(root, 0, rootNode)
+	}
+	wg.Wait()
+	if firstErr == SkipAll {
+		return nil
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return sink.err()
+}
+
+// open returns the directory of node n at path, opening it if it was not opened by the parent.
+func (n *dirNode) open(path string) (*os.File, error) {
+	if n.f != nil {
+		return n.f, nil
+	}
+	return os.Open(path)
+}
+
+// openDir opens the directory of node n at path within Walker's OpTimeout, see dirNode's open.
+func (w *Walker) openDir(n *dirNode, path string) (*os.File, error) {
+	v, err := w.timed("open", path, func() (interface{}, error) {
+		return n.open(path)
+	}, closeDir)
+	f, _ := v.(*os.File)
+	return f, err
+}
+
+// openDirAt opens subdirectory name (at path) of open directory parent within Walker's OpTimeout.
+func (w *Walker) openDirAt(parent *os.File, path string, name string) (*os.File, error) {
+	v, err := w.timed("open", path, func() (interface{}, error) {
+		return openDirAt(parent, name)
+	}, closeDir)
+	f, _ := v.(*os.File)
+	return f, err
+}
+
+// closeDir closes directory v opened by an operation, that completed after its timeout.
+func closeDir(v interface{}) {
+	if f, ok := v.(*os.File); ok && f != nil {
+		f.Close()
+	}
+}
+
+// readDirFile returns the entries of open directory f at path sorted by name within Walker's OpTimeout.
+func (w *Walker) readDirFile(path string, f *os.File) ([]fs.DirEntry, error) {
+	v, err := w.timed("readdir", path, func() (interface{}, error) {
+		return readDirFile(f)
+	}, nil)
+	entries, _ := v.([]fs.DirEntry)
+	return entries, err
+}
+
+// readDirFile returns the entries of open directory f sorted by name, like os.ReadDir.
+func readDirFile(f *os.File) ([]fs.DirEntry, error) {
+	entries, err := f.ReadDir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+// SetIgnore sets Walker's Ignore (or IgnoreRules, in IgnoreFormat IgnoreGitignore) with the contents of ignore file, like package's SetIgnore does.
+func (w *Walker) SetIgnore(ignFilePath string) error {
+	if ignFilePath == "" {
+		return nil
+	}
+	if w.IgnoreFormat == IgnoreGitignore {
+		rules, err := readGitignore(ignFilePath)
+		if err != nil {
+			return err
+		}
+		w.Ignore, w.IgnoreRules = nil, rules
+		return nil
+	}
+	ign, err := readIgnore(ignFilePath)
+	if err != nil {
+		return err
+	}
+	w.Ignore, w.IgnoreRules = ign, nil
+	return nil
+}
+
+// entryClass is what a walk does with an entry of a directory, see classify.
+type entryClass int
+
+const (
+	classSkip     entryClass = iota // the entry is left out: ignored, vanished, failed or excluded by Walker's policies
+	classSkipRest                   // the entry and the remaining entries of its directory are left out
+	classDir                        // the entry is a directory
+	classFile                       // the entry is a regular file
+	classOther                      // the entry is a special file for OtherAction
+)
+
+// classified is an entry of a directory, as classified by classify.
+type classified struct {
+	class      entryClass
+	entry      Entry
+	followed   bool // the entry is a symbolic link, that was followed
+	inSnapshot bool // the entry is a snapshot directory or inside one (see Walker's Snapshots)
+	acts       bool // the entry is to be passed to the actions
+}
+
+// classify decides, what a walk does with entry d of the directory of node, whose entries are at level:
+// it applies Walker's ignore rules and its Symlinks, Mounts and Snapshots policies, reads the entry's info as needed
+// (sizes of regular files only if sizes), and counts and publishes the entry. Vanished entries are reported and left out,
+// and errors are handled by pathError. Limiter lim, if not nil, accounts for the entry once it is not ignored.
+// Returned error stops the walk.
+func (w *Walker) classify(ctx context.Context, lim *limiter, sink *errorSink, node *dirNode, level int, d fs.DirEntry, sizes bool) (classified, error) {
+	pathName := joinPath(node.stats.Path, d.Name())
+	c := classified{entry: Entry{Path: pathName, Depth: level, d: d}}
+	if w.ignored(pathName, d.IsDir()) || node.ignore.match(pathName, d.IsDir()) {
+		atomic.AddInt64(&sink.counts.ignored, 1)
+		return c, nil
+	}
+	if lim != nil {
+		if err := lim.entry(ctx); err != nil {
+			return c, err
+		}
+	}
+	if d.Type()&os.ModeSymlink != 0 && w.Symlinks != SymlinkFail {
+		target, err := w.symlink(c.entry)
+		if err == SkipDir {
+			c.class = classSkipRest
+			return c, nil
+		} else if err != nil {
+			return c, w.pathError(sink, pathName, err)
+		}
+		if target == nil {
+			return c, nil
+		}
+		d, c.followed = infoEntry{target}, true
+		c.entry.d = d
+	}
+	switch pathType := d.Type(); {
+	case pathType.IsDir():
+		if err := w.mounts(node, pathName); err != nil {
+			return c, w.mountGuard(pathName, err)
+		}
+		// directories are statted for cycle detection and boundaries, files only when their info is needed
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			w.vanished(pathName)
+			return c, nil
+		} else if err != nil {
+			return c, w.pathError(sink, pathName, err)
+		}
+		skip, inSnapshot := w.snapshots(node, pathName, info)
+		if skip {
+			return c, nil
+		}
+		w.bus.publish(WalkEvent{Kind: EventDir, Path: pathName})
+		atomic.AddInt64(&sink.counts.dirs, 1)
+		node.stats.Dirs++
+		c.class, c.entry.info, c.inSnapshot = classDir, info, inSnapshot
+		c.acts = w.acts(inSnapshot) && w.included(c.entry)
+	case pathType.IsRegular():
+		if sizes {
+			info, err := d.Info()
+			if os.IsNotExist(err) {
+				w.vanished(pathName)
+				return c, nil
+			} else if err != nil {
+				return c, w.pathError(sink, pathName, err)
+			}
+			node.stats.Bytes += info.Size()
+			atomic.AddInt64(&sink.counts.bytes, info.Size())
+			c.entry.info = info
+		}
+		w.bus.publish(WalkEvent{Kind: EventFile, Path: pathName})
+		atomic.AddInt64(&sink.counts.files, 1)
+		node.stats.Files++
+		c.class = classFile
+		c.acts = w.acts(node.inSnapshot) && w.included(c.entry)
+	case w.OtherAction != nil && pathType&os.ModeSymlink == 0:
+		c.class = classOther
+		c.acts = w.acts(node.inSnapshot) && w.included(c.entry)
+	default:
+		return c, w.pathError(sink, pathName, fmt.Errorf("walks: invalid path type of %v", pathName))
+	}
+	return c, nil
+}
+
+// walkFile performs fileAction on root, that is a file, like a walk does on files in directories:
+// unless root is ignored, it is counted and passed to fileAction, if included in the walk.
+func (w *Walker) walkFile(root string, info fs.FileInfo, fileAction func(Entry) error, counts *walkCounts) error {
+	if w.ignored(root, false) {
+		atomic.AddInt64(&counts.ignored, 1)
+		return nil
+	}
+	w.bus.publish(WalkEvent{Kind: EventFile, Path: root})
+	atomic.AddInt64(&counts.files, 1)
+	if info != nil {
+		atomic.AddInt64(&counts.bytes, info.Size())
+	}
+	if e := (Entry{Path: root, Depth: -1, info: info}); w.acts(false) && w.included(e) {
+		if err := fileAction(e); err != SkipDir && err != SkipAll {
+			return err
+		}
+	}
+	return nil
+}
+
+// ignored reports whether path (a directory if isDir) matches Walker's Ignore or IgnoreRules (or package's ones, if neither is set).
+func (w *Walker) ignored(path string, isDir bool) bool {
+	if w.Ignore == nil && w.IgnoreRules == nil {
+		return ignoredPath(path, isDir)
+	}
+	return w.Ignore != nil && matchPath(w.Ignore, path) && w.Ignore.String() != "" || w.IgnoreRules.Match(path, isDir)
+}
+
+// included reports whether entry e is to be passed to the actions: whether its path matches Walker's Search (or package's Search, if not set)
+// and Filter accepts it.
+func (w *Walker) included(e Entry) bool {
+	search := w.Search
+	if search == nil {
+		search = Search
+	}
+	return matchPath(search, e.Path) && (w.Filter == nil || w.Filter(e.Path, e))
+}
+
+// vanished reports path removed during the walk to Vanished.
+func (w *Walker) vanished(path string) {
+	w.bus.publish(WalkEvent{Kind: EventVanished, Path: path})
+	if w.Vanished != nil {
+		w.Vanished(path)
+	}
+}
+
+// checkRoot applies Walker's RootPolicy to root, reporting whether root is a directory to walk.
+// When root is a file allowed by the policy, false is returned with no error.
+func (w *Walker) checkRoot(root string) (bool, error) {
+	pathType, err := w.stat(root)
+	if os.IsNotExist(err) && w.Root&RootCreateMissing != 0 {
+		if err := writable("mkdir", root); err != nil {
+			return false, err
+		}
+		if err := w.Require(CanWrite, "mkdir", root); err != nil {
+			return false, err
+		}
+		return true, os.MkdirAll(root, 0755)
+	}
+	if err != nil {
+		return false, err
+	}
+	if pathType.IsDir() {
+		return true, nil
+	}
+	if pathType.Mode().IsRegular() && w.Root&RootFileAsEntry != 0 {
+		return false, nil
+	}
+	return false, fmt.Errorf("walks: root %v is not a directory", root)
+}
+
+// Go starts walking given directory structure in a new goroutine and returns a Group to wait for it.
+// More concurrent work can be added to the Group with its Go method; first error of the walk or any of the work cancels the Group's context, which stops the walk.
+// Temporary workspace (see TempDir) is removed when the walk is done.
+func (w *Walker) Go(ctx context.Context, root string, fileAction func(string), dirAction func(string)) *Group {
+	g := NewGroup(ctx)
+	w.start() // counted from now on, so that Wait right after Go does not return early
+	g.Go(func() error {
+		defer w.cleanTemp()
+		defer w.finish()
+		return w.walk(g.Context(), root, plainAction(fileAction), plainAction(dirAction))
+	})
+	return g
+}
+
+// TempDir returns a scratch directory for actions to store intermediate results in.
+// The directory is created on first call, is shared by all actions of the walk and is removed with its contents when the walk is done.
+// Files written directly to TempDir are not counted towards TempQuota, use TempFile for that.
+func (w *Walker) TempDir() (string, error) {
+	w.tempMu.Lock()
+	defer w.tempMu.Unlock()
+	if w.tempDir != "" {
+		return w.tempDir, nil
+	}
+	if err := writable("mkdir", os.TempDir()); err != nil {
+		return "", err
+	}
+	if err := w.Require(CanWrite, "mkdir", os.TempDir()); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "walks-")
+	if err != nil {
+		return "", err
+	}
+	w.tempDir = dir
+	return dir, nil
+}
+
+// TempFile creates a new file in TempDir, opened for reading and writing.
+// Name of the file is generated from pattern like os.CreateTemp does.
+// Writes to the file are counted towards TempQuota.
+func (w *Walker) TempFile(pattern string) (*TempFile, error) {
+	dir, err := w.TempDir()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &TempFile{File: f, w: w}, nil
+}
+
+// cleanTemp removes temporary workspace, if it was created.
+// Nothing is removed while another walk of w is still running (eg the walk enclosing a nested one).
+func (w *Walker) cleanTemp() {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	if w.active > 0 {
+		return
+	}
+	w.tempMu.Lock()
+	defer w.tempMu.Unlock()
+	if w.tempDir == "" {
+		return
+	}
+	os.RemoveAll(w.tempDir)
+	w.tempDir = ""
+	w.tempUsed = 0
+}
+
+// reserve counts n bytes towards TempQuota, returning ErrTempQuota if the quota would be exceeded.
+func (w *Walker) reserve(n int) error {
+	w.tempMu.Lock()
+	defer w.tempMu.Unlock()
+	if w.TempQuota > 0 && w.tempUsed+int64(n) > w.TempQuota {
+		return ErrTempQuota
+	}
+	w.tempUsed += int64(n)
+	return nil
+}
+
+// TempFile is a file in Walker's temporary workspace, whose writes are counted towards Walker's TempQuota.
+type TempFile struct {
+	*os.File
+	w *Walker
+}
+
+// Write writes b to the file, unless it would exceed Walker's TempQuota, in which case ErrTempQuota is returned and nothing is written.
+func (f *TempFile) Write(b []byte) (int, error) {
+	if err := f.w.reserve(len(b)); err != nil {
+		return 0, err
+	}
+	return f.File.Write(b)
+}
+
+// WriteString is like Write, but writes the contents of string s.
+func (f *TempFile) WriteString(s string) (int, error) {
+	return f.Write([]byte(s))
+}
+
+// ReadFrom copies r to the file through Write, so that the copied bytes are counted towards TempQuota.
+func (f *TempFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
+
+// start marks a walk of w as running.
+func (w *Walker) start() {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	if w.active == 0 {
+		w.done = make(chan struct{})
+		if w.OpTimeout > 0 {
+			w.ops = newOpPool(w)
+		}
+	}
+	w.active++
+}
+
+// finish marks a walk of w as done.
+func (w *Walker) finish() {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	w.active--
+	if w.active == 0 {
+		close(w.done)
+		if w.ops != nil {
+			w.ops.close()
+			w.ops = nil
+		}
+	}
+}
+
+// Running reports whether any walk of w is in progress.
+func (w *Walker) Running() bool {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	return w.active > 0
+}
+
+// Done returns a channel, that is closed when no walk of w is in progress anymore.
+// The channel is already closed if nothing is running; call Done again after starting new walks.
+func (w *Walker) Done() <-chan struct{} {
+	w.lifeMu.Lock()
+	defer w.lifeMu.Unlock()
+	if w.done == nil {
+		w.done = make(chan struct{})
+		close(w.done)
+	}
+	return w.done
+}
+
+// Wait waits until no walk of w is in progress, eg for walks started with Go, when their Groups are not at hand.
+func (w *Walker) Wait() {
+	<-w.Done()
+}
@@ -0,0 +1,374 @@
+package walks
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// faultFS is an FS failing chosen operations of the FS it wraps:
+// listings of directories in readDirErr, and info of entries (as read from their directory's listing) in infoErr.
+type faultFS struct {
+	FS
+	readDirErr map[string]error
+	infoErr    map[string]error
+}
+
+func (f faultFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := f.readDirErr[filepath.ToSlash(name)]; err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	entries, err := f.FS.ReadDir(name)
+	for i, e := range entries {
+		path := filepath.ToSlash(filepath.Join(name, e.Name()))
+		if err := f.infoErr[path]; err != nil {
+			entries[i] = faultEntry{DirEntry: e, err: &fs.PathError{Op: "lstat", Path: path, Err: err}}
+		}
+	}
+	return entries, err
+}
+
+// faultEntry is an entry of faultFS, whose info fails with err.
+type faultEntry struct {
+	fs.DirEntry
+	err error
+}
+
+func (e faultEntry) Info() (fs.FileInfo, error) {
+	return nil, e.err
+}
+
+// testFiles are the files of the tree walked by the tests, rooted at "mem".
+func testFiles() fstest.MapFS {
+	return fstest.MapFS{
+		"mem/a.txt":          {Data: []byte("a")},
+		"mem/b.log":          {Data: []byte("bb")},
+		"mem/d1/c.txt":       {Data: []byte("ccc")},
+		"mem/d1/d2/e.txt":    {Data: []byte("eeee")},
+		"mem/d1/d2/d3/f.txt": {Data: []byte("fffff")},
+		"mem/skip/g.txt":     {Data: []byte("gggggg")},
+	}
+}
+
+// testTree returns the tree walked by the tests as an in-memory FS, see testFiles.
+func testTree() IOFS {
+	return IOFS{FS: testFiles()}
+}
+
+// testPaths returns paths given in slash form as paths of the walk, sorted.
+func testPaths(paths ...string) []string {
+	out := make([]string, 0, len(paths))
+	for _, path := range paths {
+		out = append(out, filepath.FromSlash(path))
+	}
+	sort.Strings(out)
+	return out
+}
+
+// allTestPaths are the paths of testTree passed to the actions by a walk of "mem".
+var allTestPaths = testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/d1/d2/e.txt",
+	"mem/d1/d2/d3", "mem/d1/d2/d3/f.txt", "mem/skip", "mem/skip/g.txt")
+
+// walkPaths walks root with w, returning the sorted paths passed to the actions and the error of the walk.
+// If action is not nil, it is called after the path is recorded and its result returned from the actions.
+func walkPaths(w *Walker, root string, action func(Entry) error) ([]string, error) {
+	var mu sync.Mutex
+	paths := []string{}
+	record := func(e Entry) error {
+		mu.Lock()
+		paths = append(paths, e.Path)
+		mu.Unlock()
+		if action != nil {
+			return action(e)
+		}
+		return nil
+	}
+	err := w.WalkEntries(context.Background(), root, record, record)
+	sort.Strings(paths)
+	return paths, err
+}
+
+// forModes runs test in the concurrent and in the Ordered mode of a Walker.
+func forModes(t *testing.T, test func(t *testing.T, ordered bool)) {
+	t.Run("concurrent", func(t *testing.T) { test(t, false) })
+	t.Run("ordered", func(t *testing.T) { test(t, true) })
+}
+
+func TestWalkerDepth(t *testing.T) {
+	tests := []struct {
+		depth int
+		want  []string
+	}{
+		{-1, allTestPaths},
+		{0, testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/skip")},
+		{1, testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/skip", "mem/skip/g.txt")},
+		{2, testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/d1/d2/d3", "mem/d1/d2/e.txt", "mem/skip", "mem/skip/g.txt")},
+	}
+	forModes(t, func(t *testing.T, ordered bool) {
+		for _, tt := range tests {
+			w := New(WithFS(testTree()), WithDepth(tt.depth), WithOrdered(ordered))
+			got, err := walkPaths(w, "mem", nil)
+			if err != nil {
+				t.Fatalf("depth %v: %v", tt.depth, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("depth %v: got %v, want %v", tt.depth, got, tt.want)
+			}
+		}
+	})
+}
+
+func TestWalkerIgnore(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		w := New(WithFS(testTree()), WithIgnore(regexp.MustCompile(`\.log$|/skip$`)), WithOrdered(ordered))
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := testPaths("mem/a.txt", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/d1/d2/e.txt", "mem/d1/d2/d3", "mem/d1/d2/d3/f.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		if stats := w.Stats(); stats.Ignored != 2 || stats.Files != 4 || stats.Dirs != 3 {
+			t.Errorf("got stats %+v, want 2 ignored, 4 files and 3 directories", stats)
+		}
+	})
+}
+
+func TestWalkerSearch(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// directories not matching Search are not passed to the actions, but their contents are walked
+		w := New(WithFS(testTree()), WithSearch(regexp.MustCompile(`\.txt$`)), WithOrdered(ordered))
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := testPaths("mem/a.txt", "mem/d1/c.txt", "mem/d1/d2/e.txt", "mem/d1/d2/d3/f.txt", "mem/skip/g.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestWalkerFilter(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		filter := func(path string, d fs.DirEntry) bool {
+			return d.IsDir() || strings.HasPrefix(d.Name(), "e")
+		}
+		w := New(WithFS(testTree()), WithFilter(filter), WithOrdered(ordered))
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := testPaths("mem/d1", "mem/d1/d2", "mem/d1/d2/d3", "mem/d1/d2/e.txt", "mem/skip")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestWalkerSkipDir(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// SkipDir from dirAction skips the directory's contents
+		w := New(WithFS(testTree()), WithOrdered(ordered))
+		got, err := walkPaths(w, "mem", func(e Entry) error {
+			if e.Name() == "d1" {
+				return SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/skip", "mem/skip/g.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("dirAction: got %v, want %v", got, want)
+		}
+
+		// SkipDir from fileAction skips the remaining entries of the file's directory
+		got, err = walkPaths(w, "mem", func(e Entry) error {
+			if e.Name() == "c.txt" {
+				return SkipDir
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/skip", "mem/skip/g.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("fileAction: got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestWalkerSkipAll(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		w := New(WithFS(testTree()), WithOrdered(ordered))
+		got, err := walkPaths(w, "mem", func(e Entry) error {
+			if e.Name() == "e.txt" {
+				return SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("got error %v, want nil", err)
+		}
+		if !ordered {
+			return
+		}
+		// entries come in depth-first order with sorted names, so everything after e.txt is left out
+		want := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/d1/d2/d3", "mem/d1/d2/d3/f.txt", "mem/d1/d2/e.txt")
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+	})
+}
+
+func TestWalkerErrors(t *testing.T) {
+	errBoom := errors.New("boom")
+	fsys := faultFS{FS: testTree(), readDirErr: map[string]error{"mem/d1/d2": errBoom}}
+	// the failed directory itself is walked; its contents are not
+	wantSkipped := testPaths("mem/a.txt", "mem/b.log", "mem/d1", "mem/d1/c.txt", "mem/d1/d2", "mem/skip", "mem/skip/g.txt")
+	forModes(t, func(t *testing.T, ordered bool) {
+		w := New(WithFS(fsys), WithOrdered(ordered))
+		if _, err := walkPaths(w, "mem", nil); !errors.Is(err, errBoom) {
+			t.Errorf("ErrorsStop: got error %v, want %v", err, errBoom)
+		}
+
+		w = New(WithFS(fsys), WithOrdered(ordered), WithErrors(ErrorsSkip, nil))
+		got, err := walkPaths(w, "mem", nil)
+		if err != nil {
+			t.Errorf("ErrorsSkip: got error %v, want nil", err)
+		}
+		if !reflect.DeepEqual(got, wantSkipped) {
+			t.Errorf("ErrorsSkip: got %v, want %v", got, wantSkipped)
+		}
+		if errs := w.Stats().Errors; errs != 1 {
+			t.Errorf("ErrorsSkip: got %v errors in stats, want 1", errs)
+		}
+
+		w = New(WithFS(fsys), WithOrdered(ordered), WithErrors(ErrorsCollect, nil))
+		got, err = walkPaths(w, "mem", nil)
+		var errs WalkErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || !errors.Is(errs[0], errBoom) {
+			t.Errorf("ErrorsCollect: got error %v, want WalkErrors with %v", err, errBoom)
+		}
+		if !reflect.DeepEqual(got, wantSkipped) {
+			t.Errorf("ErrorsCollect: got %v, want %v", got, wantSkipped)
+		}
+	})
+}
+
+func TestWalkerMiddlewarePost(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// middleware sees DirActionPost calls too, after the directories' contents
+		var mu sync.Mutex
+		var seen []string
+		w := New(WithFS(testTree()), WithOrdered(ordered), WithDirActionPost(func(Entry) error { return nil }))
+		w.Use(func(next func(string) error) func(string) error {
+			return func(path string) error {
+				mu.Lock()
+				seen = append(seen, path)
+				mu.Unlock()
+				return next(path)
+			}
+		})
+		if _, err := walkPaths(w, "mem", nil); err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(seen)
+		// each directory is seen by dirAction and DirActionPost, each file by fileAction
+		var want []string
+		for _, path := range allTestPaths {
+			want = append(want, path)
+			if !strings.HasSuffix(path, ".txt") && !strings.HasSuffix(path, ".log") {
+				want = append(want, path)
+			}
+		}
+		sort.Strings(want)
+		if !reflect.DeepEqual(seen, want) {
+			t.Errorf("got %v, want %v", seen, want)
+		}
+	})
+}
+
+func TestWalkFS(t *testing.T) {
+	// paths of io/fs form are walked from "." and from subdirectories
+	for _, root := range []string{".", "mem/d1"} {
+		var mu sync.Mutex
+		var got []string
+		record := func(path string) {
+			mu.Lock()
+			got = append(got, filepath.ToSlash(path))
+			mu.Unlock()
+		}
+		if err := WalkFS(context.Background(), testFiles(), root, record, record, -1); err != nil {
+			t.Fatalf("%v: %v", root, err)
+		}
+		sort.Strings(got)
+		want := []string{"./mem", "./mem/a.txt", "./mem/b.log", "./mem/d1", "./mem/d1/c.txt", "./mem/d1/d2", "./mem/d1/d2/d3",
+			"./mem/d1/d2/d3/f.txt", "./mem/d1/d2/e.txt", "./mem/skip", "./mem/skip/g.txt"}
+		if root != "." {
+			want = []string{"mem/d1/c.txt", "mem/d1/d2", "mem/d1/d2/d3", "mem/d1/d2/d3/f.txt", "mem/d1/d2/e.txt"}
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", root, got, want)
+		}
+	}
+}
+
+func TestWalkerClock(t *testing.T) {
+	forModes(t, func(t *testing.T, ordered bool) {
+		// the walk's time is told by the Walker's Clock only
+		clock := NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		w := New(WithFS(testTree()), WithOrdered(ordered), WithClock(clock))
+		if _, err := walkPaths(w, "mem", func(Entry) error {
+			clock.Advance(time.Second)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		want := time.Duration(len(allTestPaths)) * time.Second
+		if elapsed := w.Stats().Elapsed; elapsed != want {
+			t.Errorf("got elapsed %v, want %v", elapsed, want)
+		}
+		// Ordered walks do not apply Quota, nor report Usage
+		if elapsed := w.Usage().Elapsed; !ordered && elapsed != want {
+			t.Errorf("got usage elapsed %v, want %v", elapsed, want)
+		}
+	})
+}
+
+func TestWalkerWalkGlob(t *testing.T) {
+	// patterns are expanded in the Walker's FS
+	for pattern, want := range map[string][]string{
+		"mem/*.txt":    testPaths("mem/a.txt"),
+		"mem/**/e.txt": testPaths("mem/d1/d2/e.txt"),
+		"mem/d1/d2/d?": testPaths("mem/d1/d2/d3/f.txt"),
+	} {
+		var mu sync.Mutex
+		got := []string{}
+		record := func(path string) {
+			mu.Lock()
+			got = append(got, path)
+			mu.Unlock()
+		}
+		if err := New(WithFS(testTree())).WalkGlob(pattern, record, func(string) {}); err != nil {
+			t.Fatalf("%v: %v", pattern, err)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got %v, want %v", pattern, got, want)
+		}
+	}
+}
@@ -1,22 +1,28 @@
 /*
 Package walks provides functions to walk directory structure and perform user-defined actions on files and directories.
+
+Every walk tracks its own goroutines, so any number of walks can run concurrently in one process, and walks can be started from actions of other walks.
+Package-level settings (Ignore, IgnoreRules, Search, DefaultFS, DefaultClock, StrictReadOnly) are shared by all walks using them:
+set them before starting walks, or give each configuration its own Walker.
 */
 
 package walks
 
 import (
-	"io/ioutil"
+	"context"
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
-	"sync"
+	"time"
 )
 
-// WaitGroup is a variable to easily handle goroutine waiting.
-var WaitGroup sync.WaitGroup
-
 // Search is a variable to hold expressions of directories and files to search.
+// Walks perform their actions only on directories and files matching it, but still descend into directories not matching it;
+// Ignore takes precedence, ignored entries are neither acted on nor descended into.
 var Search *regexp.Regexp = regexp.MustCompile("")
 
 // Ignore is a variable to hold regexp expression of directories and files to ignore.
@@ -24,22 +30,73 @@ var Ignore *regexp.Regexp = regexp.MustCompile("")
 
 // SetIgnore sets global Ignore with the contents of ignore file,
 // where each line represents one file or directory to ignore.
+// After SetIgnoreFormat(IgnoreGitignore), lines are gitignore patterns and SetIgnore sets IgnoreRules (clearing Ignore) instead.
+// SetIgnore exits the program on error, use SetIgnoreE to handle it instead.
 func SetIgnore(ignFilePath string) {
+	if err := SetIgnoreE(ignFilePath); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// SetIgnoreE is SetIgnore, that returns errors instead of exiting the program.
+func SetIgnoreE(ignFilePath string) error {
 	if ignFilePath == "" {
-		return
+		return nil
+	}
+	if ignoreFormat == IgnoreGitignore {
+		rules, err := readGitignore(ignFilePath)
+		if err != nil {
+			return err
+		}
+		Ignore, IgnoreRules = regexp.MustCompile(""), rules
+		return nil
 	}
+	ign, err := readIgnore(ignFilePath)
+	if err != nil {
+		return err
+	}
+	Ignore, IgnoreRules = ign, nil
+	return nil
+}
+
+// readIgnoreFile returns the contents of ignore file, see SetIgnore.
+func readIgnoreFile(ignFilePath string) (string, error) {
 	tempIgn := false
 	contents, err := os.ReadFile(ignFilePath)
 	if err != nil {
 		// create temp ignore file, if does not exist, do not get an error (so that we could have default ignore file in program flag, see [ado](https://github.com/moledoc/directory/tree/main/ado)).
-		err = os.WriteFile(ignFilePath, []byte(""), 0755)
-		if err != nil {
-			log.Fatal(err)
+		// In StrictReadOnly mode nothing is created, missing ignore file is just empty.
+		if !StrictReadOnly {
+			err = os.WriteFile(ignFilePath, []byte(""), 0755)
+			if err != nil {
+				return "", err
+			}
+			tempIgn = true
 		}
-		tempIgn = true
+	}
+	if tempIgn {
+		os.RemoveAll(ignFilePath)
+	}
+	return string(contents), nil
+}
+
+// readGitignore returns the rules of ignore file in IgnoreGitignore format.
+func readGitignore(ignFilePath string) (*Gitignore, error) {
+	contents, err := readIgnoreFile(ignFilePath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseGitignore(contents, filepath.Dir(ignFilePath))
+}
+
+// readIgnore returns the regexp expression of directories and files to ignore listed in ignore file, see SetIgnore.
+func readIgnore(ignFilePath string) (*regexp.Regexp, error) {
+	contents, err := readIgnoreFile(ignFilePath)
+	if err != nil {
+		return nil, err
 	}
 	var ign string
-	for i, line := range strings.Split(string(contents), "\n") {
+	for i, line := range strings.Split(contents, "\n") {
 		if line == "" {
 			break
 		}
@@ -51,86 +108,153 @@ func SetIgnore(ignFilePath string) {
 		}
 		ign += strings.Replace(line, ".", "\\.", -1)
 	}
-	Ignore = regexp.MustCompile(ign)
-	if tempIgn {
-		os.RemoveAll(ignFilePath)
-	}
+	return regexp.Compile(ign)
 }
 
 // Walk is a concurrent function that walks recursively given directory structure, performing given actions on files and directories.
 // Actions on files and directories are expected to take the corresponding file/dir path as an argument and not return anything.
 // Directories and files can also be ignored by setting Ignore value with SetIgnore function or manually before Walk call.
+// Actions are only performed on directories and files matching Search (see Search).
 // Depth of directory structure can be controlled with variables depth.
+// Each call waits for its own goroutines only, so walks can be nested or run concurrently.
+// Walk exits the program on error, use WalkE to handle it instead.
 func Walk(root string, fileAction func(string), dirAction func(string), depth int) {
-	WaitGroup.Add(2)
-	go func() { defer WaitGroup.Done(); walk(root, fileAction, dirAction, depth, 0) }()
-	WaitGroup.Wait()
-}
-
-// walk is Walk's inner function, that actually walks the directory structure.
-// walk is concurrent.
-func walk(root string, fileAction func(string), dirAction func(string), depth int, level int) {
-	defer WaitGroup.Done()
-	if depth != -1 && level > depth {
-		return
-	}
-	if pathType, err := os.Stat(root); err != nil {
+	if err := WalkE(root, fileAction, dirAction, depth); err != nil {
 		log.Fatal(err)
-	} else if !pathType.IsDir() {
-		log.Fatal("Argument `root` must be path to a directory")
-	}
-	subpaths, err := ioutil.ReadDir(root)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, path := range subpaths {
-		pathName := root + "/" + path.Name()
-		if Ignore.MatchString(pathName) && Ignore.String() != "" {
-			continue
-		}
-		switch pathType := path.Mode(); {
-		case pathType.IsDir():
-			dirAction(pathName)
-			WaitGroup.Add(1)
-			go walk(pathName, fileAction, dirAction, depth, level+1)
-		case pathType.IsRegular():
-			fileAction(pathName)
-		default:
-			log.Fatal("Unreachable: invalid path type.")
-		}
 	}
 }
 
-// WalkLinear walks recursively given directory structure, performing given actions on files and directories.
+// WalkE is Walk, that returns errors instead of exiting the program.
+// First error stops walking further directories and is returned once all started goroutines have finished.
+func WalkE(root string, fileAction func(string), dirAction func(string), depth int) error {
+	return WalkContext(context.Background(), root, fileAction, dirAction, depth)
+}
+
+// WalkContext is WalkE, that can be cancelled, or given a deadline, with ctx.
+// When ctx is done, no more goroutines are spawned nor actions invoked, and ctx.Err() is returned as soon as the running actions return.
+// WalkContext is a thin wrapper of Walker using package's Ignore and Search; use a Walker directly to run walks with different configurations concurrently.
+func WalkContext(ctx context.Context, root string, fileAction func(string), dirAction func(string), depth int) error {
+	w := New()
+	w.Depth = depth
+	return w.WalkContext(ctx, root, fileAction, dirAction)
+}
+
+// WalkErr is WalkContext with actions, that can fail or direct the walk with SkipDir and SkipAll, like in filepath.WalkDir (see Walker's WalkErr).
+func WalkErr(ctx context.Context, root string, fileAction func(string) error, dirAction func(string) error, depth int) error {
+	w := New()
+	w.Depth = depth
+	return w.WalkErr(ctx, root, fileAction, dirAction)
+}
+
+// WalkEntries is WalkErr with actions receiving the walked Entry instead of its path, avoiding another stat of each entry (see Walker's WalkEntries).
+func WalkEntries(ctx context.Context, root string, fileAction func(Entry) error, dirAction func(Entry) error, depth int) error {
+	w := New()
+	w.Depth = depth
+	return w.WalkEntries(ctx, root, fileAction, dirAction)
+}
+
+// Stats summarizes a finished walk (see WalkLinear and Walker's Stats).
+type Stats struct {
+	// MaxDepth is the deepest level, whose directory contents were visited (the level given to WalkLinear for root itself, 0 for Walker's walks).
+	MaxDepth int
+	// Dirs is the number of directories visited, not counting the root, and Files the number of regular files visited.
+	Dirs, Files int
+	// Ignored is the number of entries left out by Ignore (and by Walker's IgnoreRules and IgnoreFiles); their contents are not counted.
+	Ignored int
+	// Errors is the number of errors of the walk itself (not of the actions), whether they stopped the walk or were skipped or collected (see Walker's Errors).
+	Errors int
+	// Bytes is the total size of regular files visited. It is only counted, when the walk reads their sizes: with Walker's CountBytes set (or DirSummary, or a subscriber of EventDirDone);
+	// WalkLinear does not count it.
+	Bytes int64
+	// Elapsed is the duration of the walk.
+	Elapsed time.Duration
+}
+
+// linearDir is a directory in WalkLinear's stack, with next being the index of its next entry to visit.
+type linearDir struct {
+	path     string
+	level    int
+	subpaths []fs.DirEntry
+	next     int
+}
+
+// WalkLinear walks given directory structure, performing given actions on files and directories.
 // Actions on files and directories are expected to take the corresponding file/dir path as an argument and not return anything.
 // Directories and files can also be ignored by setting Ignore value with SetIgnore function or setting it manually.
+// Actions are only performed on directories and files matching Search (see Search).
 // Depth of directory structure can be controlled with variables depth (and level).
-func WalkLinear(root string, fileAction func(string), dirAction func(string), depth int, level int) {
+// WalkLinear keeps the directories being walked in an explicit stack instead of recursing, so arbitrarily deep trees can be walked.
+// WalkLinear exits the program on error, use WalkLinearE to handle it instead.
+func WalkLinear(root string, fileAction func(string), dirAction func(string), depth int, level int) Stats {
+	stats, err := WalkLinearE(root, fileAction, dirAction, depth, level)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return stats
+}
+
+// WalkLinearE is WalkLinear, that returns errors instead of exiting the program.
+// First error stops the walk; stats of the walk so far are returned with it.
+func WalkLinearE(root string, fileAction func(string), dirAction func(string), depth int, level int) (stats Stats, err error) {
+	stats.MaxDepth = level
+	start := DefaultClock.Now()
+	defer func() {
+		stats.Elapsed = DefaultClock.Now().Sub(start)
+		if err != nil {
+			stats.Errors++
+		}
+	}()
 	if level == depth {
-		return
+		return stats, nil
 	}
-	if pathType, err := os.Stat(root); err != nil {
-		log.Fatal(err)
+	if pathType, err := DefaultFS.Stat(root); err != nil {
+		return stats, err
 	} else if !pathType.IsDir() {
-		log.Fatal("Argument `root` must be path to a directory")
+		return stats, fmt.Errorf("walks: root %v is not a directory", root)
 	}
-	subpaths, err := ioutil.ReadDir(root)
+	subpaths, err := DefaultFS.ReadDir(root)
 	if err != nil {
-		log.Fatal(err)
+		return stats, err
 	}
-	for _, path := range subpaths {
-		pathName := root + "/" + path.Name()
-		if Ignore.MatchString(pathName) && Ignore.String() != "" {
+	stack := []linearDir{{path: root, level: level, subpaths: subpaths}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.subpaths) {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		path := top.subpaths[top.next]
+		top.next++
+		pathName := joinPath(top.path, path.Name())
+		if ignoredPath(pathName, path.IsDir()) {
+			stats.Ignored++
 			continue
 		}
-		switch pathType := path.Mode(); {
+		switch pathType := path.Type(); {
 		case pathType.IsDir():
-			dirAction(pathName)
-			WalkLinear(pathName, fileAction, dirAction, depth, level+1)
+			stats.Dirs++
+			if matchPath(Search, pathName) {
+				dirAction(pathName)
+			}
+			if top.level+1 == depth {
+				continue
+			}
+			subpaths, err := DefaultFS.ReadDir(pathName)
+			if err != nil {
+				return stats, err
+			}
+			if top.level+1 > stats.MaxDepth {
+				stats.MaxDepth = top.level + 1
+			}
+			stack = append(stack, linearDir{path: pathName, level: top.level + 1, subpaths: subpaths})
 		case pathType.IsRegular():
-			fileAction(pathName)
+			stats.Files++
+			if matchPath(Search, pathName) {
+				fileAction(pathName)
+			}
 		default:
-			log.Fatal("Unreachable: invalid path type.")
+			return stats, fmt.Errorf("walks: invalid path type of %v", pathName)
 		}
 	}
+	return stats, nil
 }
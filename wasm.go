@@ -0,0 +1,80 @@
+package walks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// WasmRuntime executes a compiled WebAssembly module in a sandbox.
+// walks does not embed a WebAssembly engine; an implementation is expected to wrap one (such as github.com/tetratelabs/wazero),
+// instantiating the module with stdin connected to in, stdout connected to out, the file's path as its only argument
+// and no filesystem, network nor environment access.
+type WasmRuntime interface {
+	Run(ctx context.Context, module []byte, path string, in io.Reader, out io.Writer) error
+}
+
+// ErrNoWasmRuntime is returned by WasmAction when no WasmRuntime is configured.
+var ErrNoWasmRuntime = errors.New("walks: no WebAssembly runtime")
+
+// WasmAction is an experimental file action, that runs a user-provided WebAssembly module on each file,
+// streaming the file's content to the module's standard input. The module sees nothing of the filesystem beyond that content,
+// which makes it safe to run third-party processing rules, eg in multi-tenant scanners.
+type WasmAction struct {
+	// Runtime executes the module.
+	Runtime WasmRuntime
+	// Module is the compiled WebAssembly module.
+	Module []byte
+	// Output, if not nil, is called with the path and whatever the module wrote to its standard output for it.
+	Output func(path string, out []byte)
+	// Walker, if not nil, must grant CanRead and CanExec for runs of the module.
+	Walker *Walker
+
+	mu  sync.Mutex
+	err error
+}
+
+// Action returns a file action running the module with ctx on each file. Errors are available with Err.
+func (a *WasmAction) Action(ctx context.Context) func(string) {
+	return func(path string) {
+		if err := a.Run(ctx, path); err != nil {
+			a.mu.Lock()
+			if a.err == nil {
+				a.err = err
+			}
+			a.mu.Unlock()
+		}
+	}
+}
+
+// Run runs the module on file at path.
+func (a *WasmAction) Run(ctx context.Context, path string) error {
+	if a.Runtime == nil {
+		return ErrNoWasmRuntime
+	}
+	if err := a.Walker.Require(CanRead|CanExec, "wasm", path); err != nil {
+		return err
+	}
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var out bytes.Buffer
+	if err := a.Runtime.Run(ctx, a.Module, path, f, &out); err != nil {
+		return err
+	}
+	if a.Output != nil {
+		a.Output(path, out.Bytes())
+	}
+	return nil
+}
+
+// Err returns the first error of the module's runs, if any.
+func (a *WasmAction) Err() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
@@ -0,0 +1,134 @@
+package walks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Op is the kind of change reported by a watch Event.
+type Op int
+
+const (
+	OpCreate Op = iota // entry appeared
+	OpModify           // file contents (size or modification time) changed
+	OpDelete           // entry disappeared
+)
+
+// MarshalText encodes op as its name, eg in JSON.
+func (op Op) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+func (op Op) String() string {
+	switch op {
+	case OpCreate:
+		return "create"
+	case OpModify:
+		return "modify"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// Event is a change of a file or directory reported by a WatchBackend.
+type Event struct {
+	Path  string
+	Op    Op
+	IsDir bool
+}
+
+// WatchBackend is a source of filesystem events for Watch.
+type WatchBackend interface {
+	// Events returns the channel the events are delivered on; it is closed when the backend is closed.
+	Events() <-chan Event
+	// Close stops the backend.
+	Close() error
+}
+
+// Watch calls handler for every event delivered by backend, until ctx is done or backend is closed.
+// Backend is closed when Watch returns; returned error is ctx.Err() or nil if backend was closed.
+func Watch(ctx context.Context, backend WatchBackend, handler func(Event)) error {
+	defer backend.Close()
+	events := backend.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-events:
+			if !ok {
+				return nil
+			}
+			handler(ev)
+			if h, ok := backend.(interface{ handled() }); ok {
+				h.handled()
+			}
+		}
+	}
+}
+
+// Poller is a WatchBackend, that detects changes by refreshing a Snapshot of root every interval and diffing it with the previous one.
+// It works on every platform and filesystem (including network filesystems), at the cost of latency and repeated walks.
+type Poller struct {
+	events chan Event
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// NewPoller starts polling directory root every interval.
+// Entries existing when polling starts are not reported.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before NewPoller call.
+func NewPoller(root string, interval time.Duration) *Poller {
+	p := &Poller{events: make(chan Event), stop: make(chan struct{})}
+	go p.poll(root, interval)
+	return p
+}
+
+// Events implements WatchBackend.
+func (p *Poller) Events() <-chan Event {
+	return p.events
+}
+
+// Close implements WatchBackend.
+func (p *Poller) Close() error {
+	p.once.Do(func() { close(p.stop) })
+	return nil
+}
+
+// poll compares successive scans of root, sending the differences as events.
+func (p *Poller) poll(root string, interval time.Duration) {
+	defer close(p.events)
+	prev, err := TakeSnapshot(root)
+	if err != nil {
+		prev = &Snapshot{Root: root}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+		}
+		cur, err := prev.Refresh()
+		if err != nil {
+			// root is temporarily unavailable, try again on next tick.
+			continue
+		}
+		for _, ev := range prev.Diff(cur) {
+			select {
+			case p.events <- ev:
+			case <-p.stop:
+				return
+			}
+		}
+		prev = cur
+	}
+}
+
+// sortEvents sorts events by path; events of the same path keep their order.
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+}
@@ -0,0 +1,111 @@
+package walks
+
+import "sync"
+
+// FakeWatcher is a WatchBackend for testing handlers given to Watch, without touching the filesystem:
+// synthetic events are injected with Send (or Create, Modify and Delete).
+// Send returns only after the handler has processed the event, so tests can check handler's effects right after it.
+// A FakeWatcher must be closed (Watch does it when it returns) to release its goroutine.
+type FakeWatcher struct {
+	in      chan Event    // events given to Send; never closed
+	out     chan Event    // events delivered to Watch; closed by forward only
+	acks    chan struct{} // handler has processed an event
+	started chan struct{} // closed when Watch has started receiving events
+	done    chan struct{} // closed by Close
+
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewFakeWatcher returns a FakeWatcher with no events.
+func NewFakeWatcher() *FakeWatcher {
+	f := &FakeWatcher{
+		in:      make(chan Event),
+		out:     make(chan Event),
+		acks:    make(chan struct{}),
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	go f.forward()
+	return f
+}
+
+// forward passes events from Send on to Watch until the FakeWatcher is closed, then closes the channel of Events.
+func (f *FakeWatcher) forward() {
+	defer close(f.out)
+	for {
+		select {
+		case <-f.done:
+			return
+		case ev := <-f.in:
+			select {
+			case f.out <- ev:
+			case <-f.done:
+				return
+			}
+		}
+	}
+}
+
+// Started returns a channel, that is closed once Watch has started receiving events of the FakeWatcher,
+// so that a test starting Watch in a goroutine can wait for it before sending.
+func (f *FakeWatcher) Started() <-chan struct{} {
+	return f.started
+}
+
+// Send delivers ev to Watch and waits until the handler has returned, reporting whether it was handled.
+// It returns false right away, when no Watch has started with the FakeWatcher (see Started), and when the FakeWatcher is closed
+// before the event is handled (eg because Watch returned on its context being done).
+func (f *FakeWatcher) Send(ev Event) bool {
+	select {
+	case <-f.started:
+	default:
+		return false
+	}
+	select {
+	case f.in <- ev:
+	case <-f.done:
+		return false
+	}
+	select {
+	case <-f.acks:
+		return true
+	case <-f.done:
+		return false
+	}
+}
+
+// Create sends a Create event of file path.
+func (f *FakeWatcher) Create(path string) bool {
+	return f.Send(Event{Path: path, Op: OpCreate})
+}
+
+// Modify sends a Modify event of file path.
+func (f *FakeWatcher) Modify(path string) bool {
+	return f.Send(Event{Path: path, Op: OpModify})
+}
+
+// Delete sends a Delete event of file path.
+func (f *FakeWatcher) Delete(path string) bool {
+	return f.Send(Event{Path: path, Op: OpDelete})
+}
+
+// Events implements WatchBackend, marking Watch as started.
+func (f *FakeWatcher) Events() <-chan Event {
+	f.startOnce.Do(func() { close(f.started) })
+	return f.out
+}
+
+// Close implements WatchBackend, making Watch return and pending Sends fail.
+func (f *FakeWatcher) Close() error {
+	f.closeOnce.Do(func() { close(f.done) })
+	return nil
+}
+
+// handled is called by Watch after the handler has processed an event.
+func (f *FakeWatcher) handled() {
+	select {
+	case f.acks <- struct{}{}:
+	case <-f.done:
+	}
+}
@@ -0,0 +1,97 @@
+package walks
+
+import (
+	"io"
+	"sort"
+	"sync"
+)
+
+// YaraRules are compiled YARA rules. walks does not link libyara; the interface matches the ScanMem method
+// of bindings such as github.com/hillu/go-yara, wrapped to return the names of the matching rules.
+// ScanMem may be called concurrently.
+type YaraRules interface {
+	ScanMem(buf []byte) ([]string, error)
+}
+
+// YaraMatch reports the rules matching one file.
+type YaraMatch struct {
+	Path  string
+	Rules []string
+}
+
+// YaraOptions bound the resources of ScanYara.
+type YaraOptions struct {
+	// Workers is the number of files scanned in parallel; 0 means 1.
+	Workers int
+	// MaxSize is the number of leading bytes of each file scanned; 0 means 16 MiB.
+	// Memory used by the scan is bounded by Workers*MaxSize.
+	MaxSize int64
+}
+
+// ScanYara walks recursively given directory structure and applies rules to the contents of every file,
+// turning walks into the traversal engine for malware and IOC sweeps. Matches are returned sorted by path.
+// Files that cannot be read or scanned are returned in failed with their errors, instead of stopping the scan.
+// Directories and files can be ignored by setting Ignore value with SetIgnore function or manually before ScanYara call.
+func ScanYara(root string, rules YaraRules, opts YaraOptions) (matches []YaraMatch, failed map[string]error, err error) {
+	workers, maxSize := opts.Workers, opts.MaxSize
+	if workers <= 0 {
+		workers = 1
+	}
+	if maxSize <= 0 {
+		maxSize = 16 << 20
+	}
+	failed = make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, maxSize)
+			for path := range queue {
+				names, err := yaraScan(rules, path, buf)
+				mu.Lock()
+				switch {
+				case err != nil:
+					failed[path] = err
+				case len(names) > 0:
+					matches = append(matches, YaraMatch{Path: path, Rules: names})
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	it := NewIterator(root)
+	for e := it.Next(); e != nil; e = it.Next() {
+		if e.Type().IsRegular() {
+			queue <- e.Path
+		}
+	}
+	close(queue)
+	wg.Wait()
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Path < matches[j].Path })
+	return matches, failed, it.Err()
+}
+
+// ScanYara scans files under root like package's ScanYara does, if w grants CanRead (see Require).
+func (w *Walker) ScanYara(root string, rules YaraRules, opts YaraOptions) (matches []YaraMatch, failed map[string]error, err error) {
+	if err := w.Require(CanRead, "yara", root); err != nil {
+		return nil, nil, err
+	}
+	return ScanYara(root, rules, opts)
+}
+
+// yaraScan applies rules to the leading bytes of file path, read into buf.
+func yaraScan(rules YaraRules, path string, buf []byte) ([]string, error) {
+	f, err := DefaultFS.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	return rules.ScanMem(buf[:n])
+}